@@ -1,11 +1,22 @@
 import requests
 import logging
 import os
+import tempfile
+from db.db_ops import MeetingGone
 
 logger = logging.getLogger(__name__)
 
 
 WHISPER_API_URL = "http://localhost:9000"
+# When set, recordings are fetched through the backend, which decrypts them if
+# storage encryption is enabled. Otherwise they are read from the uploads dir.
+BACKEND_URL = os.getenv("BACKEND_URL")
+# Must match WORKER_TOKEN on the backend, which only accepts results from the
+# worker when this header carries it
+WORKER_TOKEN = os.getenv("WORKER_TOKEN", "")
+WORKER_TOKEN_HEADER = "X-Worker-Token"
+# Recordings downloaded from the backend stay in memory up to this size
+DOWNLOAD_SPOOL_BYTES = 64 * 1024 * 1024
 
 base_path = os.path.dirname(os.path.abspath(__file__))
 logger.info(f"Base path: {base_path}")
@@ -13,7 +24,18 @@ logger.info(f"Base path: {base_path}")
 uploads_base = os.path.abspath(os.path.join(base_path, "..", "uploads"))
 
 
-def transcribe_audio(file_path: str) -> str:
+def transcribe_audio(file_path: str, meeting_id=None, storage_profile_id=None, user_id=None,
+                     on_progress=None) -> str:
+    """on_progress(stage, percent=None) is called as the recording is
+    downloaded and handed to Whisper"""
+    on_progress = on_progress or (lambda stage, percent=None: None)
+    if BACKEND_URL and meeting_id is not None:
+        return transcribe_from_backend(meeting_id, file_path, user_id, on_progress)
+    # Recordings in a customer's own bucket are never in the uploads dir
+    if storage_profile_id:
+        raise RuntimeError(
+            f"Recording is in storage profile {storage_profile_id}, set BACKEND_URL to stream it")
+
     url = f"{WHISPER_API_URL}/asr"
 
     # Join uploads path with file_path (filename or relative path)
@@ -25,6 +47,7 @@ def transcribe_audio(file_path: str) -> str:
 
     try:
         logger.info(f"Sending audio file to Whisper: {full_file_path}")
+        on_progress("transcribing")
         with open(full_file_path, 'rb') as f:
             files = {'audio_file': (full_file_path, f, 'audio/mpeg')}
             response = requests.post(url, files=files, timeout=300)
@@ -37,3 +60,58 @@ def transcribe_audio(file_path: str) -> str:
     except Exception as e:
         logger.error(f"Transcription service failed: {e}")
         raise e
+
+
+def transcribe_from_backend(meeting_id, file_path: str, user_id, on_progress) -> str:
+    recording_url = f"{BACKEND_URL}/api/v1/meetings/{meeting_id}/recording"
+    # Meetings that belong to a user are only served to that user
+    headers = {'X-User-ID': str(user_id)} if user_id is not None else {}
+
+    try:
+        logger.info(f"Downloading recording for Whisper from: {recording_url}")
+        with requests.get(recording_url, headers=headers, stream=True, timeout=300) as recording:
+            recording.raise_for_status()
+            content_type = recording.headers.get('Content-Type', 'audio/mpeg')
+            # Download to a spooled file first, so progress can be reported
+            total = int(recording.headers.get('Content-Length') or 0)
+            audio = tempfile.SpooledTemporaryFile(max_size=DOWNLOAD_SPOOL_BYTES)
+            on_progress("downloading", 0 if total else None)
+            for chunk in recording.iter_content(chunk_size=1 << 20):
+                audio.write(chunk)
+                if total:
+                    on_progress("downloading", min(100, audio.tell() * 100 // total))
+
+        with audio:
+            audio.seek(0)
+            on_progress("transcribing")
+            files = {'audio_file': (os.path.basename(file_path), audio, content_type)}
+            response = requests.post(f"{WHISPER_API_URL}/asr", files=files, timeout=300)
+
+        response.raise_for_status()
+        result = response.text
+        logger.info(f"Transcription result: {result}")
+        return result
+
+    except Exception as e:
+        logger.error(f"Transcription service failed: {e}")
+        raise e
+
+
+def store_transcript(meeting_id, transcript: str, user_id=None) -> dict:
+    """Saves the transcript through the backend, which keeps large ones in
+    object storage instead of the meetings table."""
+    transcript_url = f"{BACKEND_URL}/api/v1/meetings/{meeting_id}/transcript"
+    headers = {'Content-Type': 'text/plain; charset=utf-8',
+               WORKER_TOKEN_HEADER: WORKER_TOKEN}
+    if user_id is not None:
+        headers['X-User-ID'] = str(user_id)
+
+    response = requests.put(
+        transcript_url, data=transcript.encode('utf-8'), headers=headers, timeout=60)
+    if response.status_code == 404:
+        raise MeetingGone(f"Meeting {meeting_id} not found")
+    response.raise_for_status()
+    result = response.json()
+    if result.get('transcript_key'):
+        logger.info(f"Transcript offloaded to {result['transcript_key']}")
+    return result
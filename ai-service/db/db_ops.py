@@ -1,6 +1,13 @@
 from sqlalchemy.orm import Session
 from sqlalchemy import update
+from datetime import datetime, timezone
 from models.meeting import Meeting, MeetingStatus
+from models.dead_letter import DeadLetter
+
+
+class MeetingGone(Exception):
+    """The meeting was deleted, or is being deleted, while its job ran.
+    There is nothing left to process, so the job is dropped."""
 
 
 def update_meeting_status(
@@ -10,9 +17,11 @@ def update_meeting_status(
     **fields
 
 ):
+    # A meeting being deleted must stay hidden until the backend removes it
     stmt = (
         update(Meeting)
         .where(Meeting.id == meeting_id)
+        .where(Meeting.status != MeetingStatus.deleting)
         .values(
             status=status,
             **fields
@@ -20,7 +29,8 @@ def update_meeting_status(
     )
     result = db.execute(stmt)
     if result.rowcount == 0:
-        raise ValueError(f"Meeting {meeting_id} not found")
+        db.rollback()
+        raise MeetingGone(f"Meeting {meeting_id} not found")
     db.commit()
 
 
@@ -32,33 +42,67 @@ def save_results(
     action_items: list | None,
     key_points: list | None = None,
 ):
+    values = dict(
+        summary=summary,
+        action_items=action_items,
+        key_points=key_points,
+        status=MeetingStatus.completed,
+    )
+    # None leaves the transcript alone, e.g. when it was already stored
+    # through the backend. Otherwise it is written inline and the backend
+    # offloads it later if it is too large.
+    if transcript is not None:
+        values.update(
+            transcript=transcript,
+            transcript_key=None,
+            transcript_size_bytes=len(transcript.encode("utf-8")),
+        )
+
     stmt = (
         update(Meeting)
         .where(Meeting.id == meeting_id)
-        .values(
-            transcript=transcript,
-            summary=summary,
-            action_items=action_items,
-            key_points=key_points,
-            status=MeetingStatus.completed,
-        )
+        .where(Meeting.status != MeetingStatus.deleting)
+        .values(**values)
     )
 
     result = db.execute(stmt)
     if result.rowcount == 0:
-        raise ValueError(f"Meeting {meeting_id} not found")
+        db.rollback()
+        raise MeetingGone(f"Meeting {meeting_id} not found")
     db.commit()
 
 
-def mark_failed(db: Session, meeting_id: int):
+def mark_failed(
+    db: Session,
+    meeting_id: int,
+    job_data: dict,
+    error: str,
+    stack: str | None = None,
+    worker_id: str | None = None,
+):
+    """Fails the meeting for good and keeps the job as a dead letter, in one
+    transaction."""
     stmt = (
         update(Meeting)
         .where(Meeting.id == meeting_id)
+        .where(Meeting.status.in_([
+            MeetingStatus.created,
+            MeetingStatus.queued,
+            MeetingStatus.processing,
+        ]))
         .values(
             status=MeetingStatus.failed,
+            failure_reason=error,
         )
     )
-    result = db.execute(stmt)
-    if result.rowcount == 0:
-        raise ValueError(f"Meeting {meeting_id} not found")
+    db.execute(stmt)
+    db.add(DeadLetter(
+        meeting_id=meeting_id,
+        payload=job_data,
+        error=error,
+        stack=stack,
+        attempts=job_data.get('attempts', 0) + 1,
+        worker_id=worker_id,
+        failed_at=datetime.now(timezone.utc),
+    ))
     db.commit()
@@ -0,0 +1,171 @@
+import os
+import json
+import time
+import logging
+import redis
+from datetime import datetime, timezone
+from sqlalchemy import select, update, delete
+from models.queue_job import QueueJob
+from db.session import SessionLocal
+
+logger = logging.getLogger(__name__)
+
+# Must match QUEUE_BACKEND on the backend. Redis is needed with either
+# backend, since meeting events are published through it.
+QUEUE_BACKEND = os.getenv('QUEUE_BACKEND', 'redis')
+QUEUE_NAME = "meeting_jobs"
+
+# Moves a job back onto the queue unless the reaper already did
+REQUEUE_SCRIPT = """
+if redis.call('LREM', KEYS[1], 1, ARGV[1]) == 1 then
+    redis.call('RPUSH', KEYS[2], ARGV[2])
+    return 1
+end
+return 0
+"""
+
+
+class RedisJobQueue:
+    """Jobs are moved onto this worker's processing list while they run, and
+    only removed once they finish. The backend's reaper puts them back on the
+    queue if the heartbeat stops, so a crashed worker doesn't lose its job.
+    A delivery's receipt is the raw payload."""
+
+    def __init__(self, worker_id, host, port):
+        self.worker_id = worker_id
+        self.processing_key = f"{QUEUE_NAME}:processing:{worker_id}"
+        self.heartbeats_key = f"{QUEUE_NAME}:heartbeats"
+        # Holds a job's identity while it is queued or running, so the backend
+        # doesn't queue it twice. Released once the job is done or has failed
+        # for good.
+        self.dedup_key_prefix = f"{QUEUE_NAME}:dedup:"
+        self.r = redis.Redis(host=host, port=port, decode_responses=True)
+        self.r.ping()
+
+    def dequeue(self, wait):
+        """Returns (receipt, job_data), or None if no job came within wait seconds"""
+        raw_data = self.r.blmove(QUEUE_NAME, self.processing_key,
+                                 wait, src='LEFT', dest='RIGHT')
+        if not raw_data:
+            return None
+        try:
+            return raw_data, json.loads(raw_data)
+        except json.JSONDecodeError:
+            logger.error(f"Failed to decode JSON: {raw_data}")
+            self.r.lrem(self.processing_key, 1, raw_data)
+            return None
+
+    def ack(self, receipt, job_data):
+        """Drops a job that is done or has failed for good"""
+        self.r.lrem(self.processing_key, 1, receipt)
+        if job_data.get('job_id'):
+            self.r.delete(self.dedup_key_prefix + job_data['job_id'])
+
+    def nack(self, receipt, job_data):
+        """Puts a job back on the queue with one more attempt counted"""
+        retry = dict(job_data, attempts=job_data.get('attempts', 0) + 1)
+        self.r.eval(REQUEUE_SCRIPT, 2, self.processing_key,
+                    QUEUE_NAME, receipt, json.dumps(retry))
+
+    def heartbeat(self):
+        self.r.hset(self.heartbeats_key, self.worker_id, time.time())
+
+    def leftovers(self):
+        """Jobs a previous run with the same worker ID didn't finish"""
+        held = []
+        for raw_data in self.r.lrange(self.processing_key, 0, -1):
+            try:
+                held.append((raw_data, json.loads(raw_data)))
+            except json.JSONDecodeError:
+                self.r.lrem(self.processing_key, 1, raw_data)
+        return held
+
+    def close(self):
+        self.r.hdel(self.heartbeats_key, self.worker_id)
+
+
+class PostgresJobQueue:
+    """Jobs are rows of the backend's queue_jobs table. A worker claims one
+    with FOR UPDATE SKIP LOCKED by setting itself as the consumer. A
+    delivery's receipt is the row ID."""
+
+    POLL_INTERVAL = 0.5
+
+    def __init__(self, worker_id):
+        self.worker_id = worker_id
+
+    def dequeue(self, wait):
+        """Returns (receipt, job_data), or None if no job came within wait seconds"""
+        deadline = time.monotonic() + wait
+        while True:
+            delivery = self._claim()
+            if delivery or time.monotonic() >= deadline:
+                return delivery
+            time.sleep(min(self.POLL_INTERVAL, deadline - time.monotonic()))
+
+    def _claim(self):
+        with SessionLocal() as db:
+            job = db.execute(
+                select(QueueJob)
+                .where(QueueJob.consumer.is_(None))
+                .order_by(QueueJob.id)
+                .limit(1)
+                .with_for_update(skip_locked=True)
+            ).scalar_one_or_none()
+            if job is None:
+                return None
+            job.consumer = self.worker_id
+            job.heartbeat_at = datetime.now(timezone.utc)
+            db.commit()
+            return job.id, dict(job.payload, attempts=job.attempts)
+
+    def ack(self, receipt, job_data):
+        """Drops a job that is done or has failed for good"""
+        self._execute(delete(QueueJob).where(
+            QueueJob.id == receipt, QueueJob.consumer == self.worker_id))
+
+    def nack(self, receipt, job_data):
+        """Puts a job back on the queue with one more attempt counted"""
+        self._execute(
+            update(QueueJob)
+            .where(QueueJob.id == receipt, QueueJob.consumer == self.worker_id)
+            .values(attempts=QueueJob.attempts + 1, consumer=None, heartbeat_at=None)
+        )
+
+    def heartbeat(self):
+        self._execute(
+            update(QueueJob)
+            .where(QueueJob.consumer == self.worker_id)
+            .values(heartbeat_at=datetime.now(timezone.utc))
+        )
+
+    def leftovers(self):
+        """Jobs a previous run with the same worker ID didn't finish"""
+        with SessionLocal() as db:
+            jobs = db.execute(
+                select(QueueJob).where(QueueJob.consumer == self.worker_id)
+            ).scalars().all()
+            return [(job.id, dict(job.payload, attempts=job.attempts)) for job in jobs]
+
+    def close(self):
+        pass
+
+    def _execute(self, stmt):
+        with SessionLocal() as db:
+            db.execute(stmt)
+            db.commit()
+
+
+def open_queue(worker_id):
+    """Connects to the queue backend named in QUEUE_BACKEND"""
+    if QUEUE_BACKEND in ('redis', ''):
+        return RedisJobQueue(
+            worker_id,
+            host=os.getenv('REDIS_HOST', 'localhost'),
+            port=int(os.getenv('REDIS_PORT', '6379')),
+        )
+    if QUEUE_BACKEND == 'postgres':
+        return PostgresJobQueue(worker_id)
+    # The memory backend lives inside the backend process
+    raise ValueError(
+        f"Queue backend '{QUEUE_BACKEND}' can't be used by the worker")
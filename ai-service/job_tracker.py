@@ -0,0 +1,56 @@
+import time
+import logging
+import requests
+from contextlib import contextmanager
+from datetime import datetime, timezone
+from audio_processor import BACKEND_URL, WORKER_TOKEN, WORKER_TOKEN_HEADER
+
+logger = logging.getLogger(__name__)
+
+# Reporting is best effort: a job never fails because its history couldn't
+# be written. Without BACKEND_URL nothing is reported.
+TIMEOUT = 10
+
+
+def start_job(job_data, worker_id):
+    """Records that this worker took the job, and returns the ID of the
+    attempt to report on, or None"""
+    result = _post("/api/v1/jobs", {'job': job_data, 'worker_id': worker_id})
+    return result.get('id') if result else None
+
+
+def finish_job(attempt_id, state, error=None, retry=False):
+    """Records how an attempt ended; retry means it goes back on the queue"""
+    if attempt_id is None:
+        return
+    _post(f"/api/v1/jobs/{attempt_id}/finish",
+          {'state': state, 'error': error, 'retry': retry})
+
+
+@contextmanager
+def job_stage(attempt_id, stage):
+    """Times the stage inside the with block and reports it once it ends"""
+    started_at = datetime.now(timezone.utc)
+    start = time.monotonic()
+    try:
+        yield
+    finally:
+        if attempt_id is not None:
+            _post(f"/api/v1/jobs/{attempt_id}/stages", {
+                'stage': stage,
+                'started_at': started_at.isoformat(),
+                'duration_ms': int((time.monotonic() - start) * 1000),
+            })
+
+
+def _post(path, body):
+    if not BACKEND_URL:
+        return None
+    try:
+        response = requests.post(f"{BACKEND_URL}{path}", json=body,
+                                 headers={WORKER_TOKEN_HEADER: WORKER_TOKEN}, timeout=TIMEOUT)
+        response.raise_for_status()
+        return response.json()
+    except Exception as e:
+        logger.error(f"Failed to report job progress to {path}: {e}")
+        return None
@@ -1,15 +1,18 @@
 from models.meeting import MeetingStatus
-from db.db_ops import update_meeting_status, save_results, mark_failed
+from db.db_ops import update_meeting_status, save_results, mark_failed, MeetingGone
 from db.session import SessionLocal
 import os
-import json
-import time
 import signal
-import redis
+import socket
+import threading
+import traceback
 import logging
 from dotenv import load_dotenv
-from audio_processor import transcribe_audio
+from audio_processor import BACKEND_URL, transcribe_audio, store_transcript
 from llm_processor import generate_summary
+from job_queue import QUEUE_BACKEND, open_queue
+from job_tracker import start_job, finish_job, job_stage
+from meeting_events import progress_reporter, report_status
 
 # Load environment variables FIRST, before any other imports that depend on them
 load_dotenv()
@@ -21,63 +24,132 @@ logging.basicConfig(level=logging.INFO,
                     format='%(asctime)s - %(levelname)s - %(message)s')
 logger = logging.getLogger(__name__)
 
-REDIS_HOST = os.getenv('REDIS_HOST', 'localhost')
-REDIS_PORT = int(os.getenv('REDIS_PORT', '6379'))
-QUEUE_NAME = "meeting_jobs"
+# Identifies this worker to the queue, which hands its jobs to another worker
+# if its heartbeat stops
+WORKER_ID = os.getenv('WORKER_ID') or f"{socket.gethostname()}-{os.getpid()}"
+HEARTBEAT_INTERVAL = int(os.getenv('HEARTBEAT_INTERVAL', '10'))
+# Used for jobs queued before max_attempts was part of the payload
+DEFAULT_MAX_ATTEMPTS = 3
 
 # Global flag for graceful shutdown
 shutdown_flag = False
 
 
-def process_meeting_job(job_data):
+def process_meeting_job(job_data, attempt_id=None):
+    """Runs one job. Errors are raised so the caller can retry or fail it.
+    Stage timings are reported on attempt_id."""
     # Job data uses 'id'
     meeting_id = job_data.get('id')
     file_path = job_data.get('file_path')
 
     if not meeting_id:
-        logger.error(f"Missing meeting_id in job_data: {job_data}")
-        return
+        raise ValueError(f"Missing meeting_id in job_data: {job_data}")
 
-    logger.info(f"Starting job for meeting {meeting_id}")
+    logger.info(
+        f"Starting job for meeting {meeting_id} (attempt {job_data.get('attempts', 0) + 1})")
 
     # Create database session
     db = SessionLocal()
     try:
         # 1.Update DB -> processing
         update_meeting_status(db, meeting_id, MeetingStatus.processing)
+        report_status(meeting_id, MeetingStatus.processing.value)
         logger.info(f"Status updated to processing for {meeting_id}")
+        on_progress = progress_reporter(meeting_id)
 
         # 2. Transcribe (Whisper)
         logger.info("🎙️ Starting Transcription...")
-        transcript = transcribe_audio(file_path)
+        with job_stage(attempt_id, "transcribe"):
+            transcript = transcribe_audio(
+                file_path,
+                meeting_id,
+                storage_profile_id=job_data.get('storage_profile_id'),
+                user_id=job_data.get('user_id'),
+                on_progress=on_progress,
+            )
 
         if not transcript:
-            logger.error(f"No transcript found for {meeting_id}")
-            return
+            raise RuntimeError(f"No transcript found for {meeting_id}")
         # 3. Summarize (Ollama)
         logger.info("🧠 Generating Summary with Ollama...")
-        result = generate_summary(transcript)
+        on_progress("summarizing")
+        with job_stage(attempt_id, "summarize"):
+            result = generate_summary(transcript)
         summary = result.get("summary", "")
         action_items = result.get("action_items", [])
 
-        # 4. Update DB -> Completed
-        save_results(
-            db,
-            meeting_id,
-            transcript=transcript,
-            summary=summary,
-            action_items=action_items
-        )
+        # 4. Store the transcript through the backend when we can reach it,
+        # so large transcripts never land in Postgres
+        on_progress("storing")
+        with job_stage(attempt_id, "store"):
+            if BACKEND_URL:
+                store_transcript(meeting_id, transcript,
+                                 job_data.get('user_id'))
+                transcript = None
+
+            # 5. Update DB -> Completed
+            save_results(
+                db,
+                meeting_id,
+                transcript=transcript,
+                summary=summary,
+                action_items=action_items
+            )
+        report_status(meeting_id, MeetingStatus.completed.value)
         logger.info(f"✅ Job {meeting_id} Completed Successfully")
+    finally:
+        db.close()
 
-    except Exception as e:
-        logger.error(f"❌ Job Failed: {str(e)}")
+
+def retry_or_fail(queue, receipt, job_data, error, stack=None, attempt_id=None):
+    """Puts a failed job back on the queue, or dead-letters it once it has
+    used up its attempts."""
+    meeting_id = job_data.get('id')
+    attempts = job_data.get('attempts', 0) + 1
+    max_attempts = job_data.get('max_attempts') or DEFAULT_MAX_ATTEMPTS
+
+    if meeting_id and attempts < max_attempts:
+        # Recorded first, so the next attempt is in the history before any
+        # worker can take it
+        finish_job(attempt_id, "failed", error, retry=True)
+        queue.nack(receipt, job_data)
+        logger.info(
+            f"Job {meeting_id} requeued, attempt {attempts} of {max_attempts} failed")
+        return
+
+    finish_job(attempt_id, "failed", error)
+    if meeting_id:
+        db = SessionLocal()
         try:
-            mark_failed(db, meeting_id)
+            mark_failed(db, meeting_id, job_data, error,
+                        stack=stack, worker_id=WORKER_ID)
+            report_status(meeting_id, MeetingStatus.failed.value, error)
         except Exception as db_error:
             logger.error(f"Failed to mark meeting as failed: {db_error}")
-    finally:
-        db.close()
+        finally:
+            db.close()
+    queue.ack(receipt, job_data)
+    logger.error(f"Job {meeting_id} failed after {attempts} attempts")
+
+
+def send_heartbeats(queue, stop):
+    """Tells the backend this worker is alive until stop is set"""
+    while not stop.is_set():
+        try:
+            queue.heartbeat()
+        except Exception as e:
+            logger.error(f"Failed to send heartbeat: {e}")
+        stop.wait(HEARTBEAT_INTERVAL)
+
+
+def requeue_leftovers(queue):
+    """Returns jobs a previous run with the same WORKER_ID didn't finish"""
+    for receipt, job_data in queue.leftovers():
+        logger.info(
+            f"Recovering unfinished job for meeting_id: {job_data.get('id')}")
+        retry_or_fail(queue, receipt, job_data,
+                      f"worker {WORKER_ID} restarted during the job",
+                      attempt_id=start_job(job_data, WORKER_ID))
 
 
 def signal_handler(sig, frame):
@@ -95,34 +167,52 @@ def start_consumer():
     signal.signal(signal.SIGINT, signal_handler)
     signal.signal(signal.SIGTERM, signal_handler)
 
-    # Connect to Redis
+    # Connect to the queue
     try:
-        r = redis.Redis(host=REDIS_HOST, port=REDIS_PORT,
-                        decode_responses=True)
-        r.ping()
-        logger.info("✅ Connected to Redis")
+        queue = open_queue(WORKER_ID)
+        logger.info(f"✅ Connected to the {QUEUE_BACKEND} queue")
     except Exception as e:
-        logger.error(f"Failed to connect to Redis: {e}")
+        logger.error(f"Failed to connect to the queue: {e}")
         return
 
-    logger.info(f"🎧 Waiting for jobs in queue: '{QUEUE_NAME}'...")
+    # Heartbeat before taking any job, so the reaper never sees a job
+    # without a live worker
+    stop_heartbeats = threading.Event()
+    queue.heartbeat()
+    threading.Thread(target=send_heartbeats, args=(
+        queue, stop_heartbeats), daemon=True).start()
+    requeue_leftovers(queue)
+
+    logger.info(
+        f"🎧 Worker {WORKER_ID} waiting for jobs on the {QUEUE_BACKEND} queue...")
     logger.info("Press Ctrl+C to stop gracefully")
 
     while not shutdown_flag:
         try:
             # Use a timeout so we can check shutdown_flag periodically
             # timeout=5 means check every 5 seconds
-            task = r.blpop(QUEUE_NAME, timeout=5)
+            delivery = queue.dequeue(5)
 
-            if task:
-                queue, raw_data = task
+            if delivery:
+                receipt, job_data = delivery
+                logger.info(
+                    f"Job received for meeting_id: {job_data.get('id')}")
+                attempt_id = start_job(job_data, WORKER_ID)
                 try:
-                    job_data = json.loads(raw_data)
-                    logger.info(
-                        f"Job received for meeting_id: {job_data.get('id')}")
-                    process_meeting_job(job_data)
-                except json.JSONDecodeError:
-                    logger.error(f"Failed to decode JSON: {raw_data}")
+                    process_meeting_job(job_data, attempt_id)
+                except MeetingGone as e:
+                    # Deleted while it ran: nothing to retry or dead-letter
+                    logger.info(f"Dropping job: {e}")
+                    finish_job(attempt_id, "failed", str(e))
+                    queue.ack(receipt, job_data)
+                except Exception as e:
+                    logger.error(f"❌ Job Failed: {str(e)}")
+                    retry_or_fail(queue, receipt, job_data, str(e) or type(e).__name__,
+                                  stack=traceback.format_exc(), attempt_id=attempt_id)
+                else:
+                    finish_job(attempt_id, "succeeded")
+                    # Acknowledge: the job is done and leaves the queue
+                    queue.ack(receipt, job_data)
         except KeyboardInterrupt:
             # This should be caught by signal handler, but just in case
             break
@@ -130,6 +220,8 @@ def start_consumer():
             if not shutdown_flag:
                 logger.error(f"Error in consumer loop: {e}")
 
+    stop_heartbeats.set()
+    queue.close()
     logger.info("👋 Consumer stopped gracefully")
 
 
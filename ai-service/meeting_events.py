@@ -0,0 +1,88 @@
+import os
+import json
+import logging
+import redis
+from datetime import datetime, timezone
+
+logger = logging.getLogger(__name__)
+
+# A meeting's events are published on its channel and kept on a short log,
+# so the backend can replay them to clients that reconnect. The key names
+# and script match the backend.
+EVENTS_PREFIX = "meeting_events:"
+EVENTS_KEPT = 200
+EVENTS_TTL = 24 * 60 * 60
+
+# Gives the event an increasing ID, logs it and publishes it
+PUBLISH_EVENT_SCRIPT = """
+local now = redis.call('TIME')
+local id = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+local last = tonumber(redis.call('GET', KEYS[1]) or '0')
+if id <= last then
+    id = last + 1
+end
+redis.call('SET', KEYS[1], id, 'EX', ARGV[3])
+
+local event = cjson.decode(ARGV[1])
+event['id'] = id
+local payload = cjson.encode(event)
+redis.call('RPUSH', KEYS[2], payload)
+redis.call('LTRIM', KEYS[2], -tonumber(ARGV[2]), -1)
+redis.call('EXPIRE', KEYS[2], ARGV[3])
+redis.call('PUBLISH', KEYS[3], payload)
+return id
+"""
+
+_client = None
+
+
+def _redis():
+    global _client
+    if _client is None:
+        _client = redis.Redis(host=os.getenv('REDIS_HOST', 'localhost'),
+                              port=int(os.getenv('REDIS_PORT', '6379')),
+                              decode_responses=True)
+    return _client
+
+
+def publish_event(meeting_id, event):
+    """Publishes an event for the meeting. Events are best effort: a failure
+    is logged and never fails the job."""
+    event = dict(event, at=datetime.now(timezone.utc).isoformat())
+    channel = f"{EVENTS_PREFIX}{meeting_id}"
+    try:
+        _redis().eval(PUBLISH_EVENT_SCRIPT, 3, f"{channel}:seq", f"{channel}:log", channel,
+                      json.dumps(event), EVENTS_KEPT, EVENTS_TTL)
+    except Exception as e:
+        logger.error(f"Failed to publish event for meeting {meeting_id}: {e}")
+
+
+def report_progress(meeting_id, stage, percent=None):
+    """Tells followers which stage the job is in, and how far along it is
+    when that is known"""
+    event = {'type': 'progress', 'stage': stage}
+    if percent is not None:
+        event['percent'] = int(percent)
+    publish_event(meeting_id, event)
+
+
+def report_status(meeting_id, status, message=None):
+    event = {'type': 'status', 'status': status}
+    if message:
+        event['message'] = message
+    publish_event(meeting_id, event)
+
+
+def progress_reporter(meeting_id, step=5):
+    """Returns an on_progress(stage, percent) callback that publishes stage
+    changes, and percentages only once they have moved by step"""
+    last = {'stage': None, 'percent': None}
+
+    def on_progress(stage, percent=None):
+        if stage == last['stage'] and (percent is None or (
+                last['percent'] is not None and percent < last['percent'] + step and percent < 100)):
+            return
+        last['stage'], last['percent'] = stage, percent
+        report_progress(meeting_id, stage, percent)
+
+    return on_progress
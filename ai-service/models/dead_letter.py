@@ -0,0 +1,21 @@
+from sqlalchemy import Column, Integer, String, Text, DateTime, func
+from sqlalchemy.dialects.postgresql import JSONB
+from db.session import Base
+
+
+class DeadLetter(Base):
+    """A job that failed for good. The table is owned by the backend, which
+    lists and replays dead letters through its admin API."""
+    __tablename__ = "dead_letters"
+
+    id = Column(Integer, primary_key=True, index=True)
+    meeting_id = Column(Integer, nullable=False, index=True)
+    payload = Column(JSONB, nullable=False)
+    error = Column(Text, nullable=False)
+    stack = Column(Text)
+    attempts = Column(Integer, nullable=False)
+    worker_id = Column(String(255))
+    failed_at = Column(DateTime(timezone=True), nullable=False)
+    replayed_at = Column(DateTime(timezone=True))
+
+    created_at = Column(DateTime(timezone=True), server_default=func.now())
@@ -1,4 +1,5 @@
 from sqlalchemy import (
+    Boolean,
     Column,
     Integer,
     String,
@@ -17,9 +18,11 @@ import enum
 
 class MeetingStatus(str, enum.Enum):
     created = "created"
+    queued = "queued"
     processing = "processing"
     completed = "completed"
     failed = "failed"
+    deleting = "deleting"
 
 
 class Meeting(Base):
@@ -37,12 +40,20 @@ class Meeting(Base):
     scheduled_at = Column(DateTime(timezone=True))
 
     # Recording Details
+    recording_file_id = Column(Integer, index=True)
     recording_path = Column(String(500))
     recording_size_bytes = Column(BigInteger)
     recording_duration_seconds = Column(Integer)
+    recording_mime_type = Column(String(100))
+    recording_sample_rate = Column(Integer)
+    recording_channels = Column(Integer)
+    recording_bitrate = Column(Integer)
 
-    # AI Results
+    # AI Results. Large transcripts are offloaded to object storage by the
+    # backend, which then stores the key in transcript_key and clears transcript.
     transcript = Column(Text)
+    transcript_key = Column(String(500))
+    transcript_size_bytes = Column(BigInteger)
     summary = Column(Text)
 
     # JSONB Fields
@@ -56,10 +67,19 @@ class Meeting(Base):
         server_default=text("'created'::meeting_status"),
         index=True
     )
+    # Why processing failed for good
+    failure_reason = Column(Text)
 
     # Nullable user
     user_id = Column(Integer)
 
+    # Retention, managed by the backend
+    legal_hold = Column(Boolean, nullable=False, server_default=text("false"))
+    recording_retention_days = Column(Integer)
+    transcript_retention_days = Column(Integer)
+    recording_expired_at = Column(DateTime(timezone=True))
+    transcript_expired_at = Column(DateTime(timezone=True))
+
     # Timestamps
     created_at = Column(DateTime(timezone=True), server_default=func.now())
     updated_at = Column(
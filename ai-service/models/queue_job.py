@@ -0,0 +1,18 @@
+from sqlalchemy import Column, Integer, String, DateTime, func
+from sqlalchemy.dialects.postgresql import JSONB
+from db.session import Base
+
+
+class QueueJob(Base):
+    """A job on the Postgres queue, used when QUEUE_BACKEND=postgres. The
+    table is owned by the backend; a row is claimed while consumer is set."""
+    __tablename__ = "queue_jobs"
+
+    id = Column(Integer, primary_key=True, index=True)
+    job_id = Column(String(600), nullable=False, unique=True)
+    payload = Column(JSONB, nullable=False)
+    attempts = Column(Integer, nullable=False, default=0)
+    consumer = Column(String(255), index=True)
+    heartbeat_at = Column(DateTime(timezone=True))
+
+    created_at = Column(DateTime(timezone=True), server_default=func.now())
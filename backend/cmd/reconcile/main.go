@@ -0,0 +1,86 @@
+// Command reconcile finds objects in the recordings bucket that no meeting
+// references. It only reports by default; pass -dry-run=false to delete them.
+// The default bucket is scanned unless -prefix names a storage profile.
+//
+//	go run ./cmd/reconcile -grace 72h -dry-run=false
+//	go run ./cmd/reconcile -prefix profiles/12/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/config"
+	"github.com/jaykapade/meeting-assistant/backend/internal/db"
+	"github.com/jaykapade/meeting-assistant/backend/internal/services"
+	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", true, "Only report orphans, don't delete them")
+	grace := flag.Duration("grace", 24*time.Hour, "Ignore objects modified within this period")
+	prefix := flag.String("prefix", "", "Only scan keys with this prefix")
+	asJSON := flag.Bool("json", false, "Print the full report as JSON")
+	flag.Parse()
+
+	cfg := config.Load()
+
+	dbConn, err := db.ConnectDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	ctx := context.Background()
+	defaultStore, err := storage.NewProvider(ctx, cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	profiles, err := services.NewStorageProfileService(dbConn, defaultStore, cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage profiles: %v", err)
+	}
+
+	collector := services.NewOrphanCollector(dbConn, storage.NewRouter(defaultStore, profiles))
+	report, err := collector.Run(ctx, services.OrphanOptions{
+		GracePeriod: *grace,
+		DryRun:      *dryRun,
+		Prefix:      *prefix,
+	})
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	} else {
+		for _, orphan := range report.Orphans {
+			status := "orphan"
+			switch {
+			case orphan.Deleted:
+				status = "deleted"
+			case orphan.Error != "":
+				status = "skipped: " + orphan.Error
+			}
+			log.Printf("%s (%d bytes, modified %s) %s", orphan.Key, orphan.Size, orphan.LastModified.Format(time.RFC3339), status)
+		}
+		log.Printf("Scanned %d objects: %d referenced, %d within the %s grace period, %d orphaned (%d bytes)",
+			report.Scanned, report.Referenced, report.Recent, report.GracePeriod, len(report.Orphans), report.OrphanBytes)
+		if report.DryRun {
+			log.Printf("Dry run, nothing was deleted. Re-run with -dry-run=false to delete orphans")
+		} else {
+			log.Printf("Deleted %d orphans, %d failed", report.Deleted, report.Failed)
+		}
+	}
+
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,53 @@
+// Command repair-replicas copies every object that a storage replica is
+// missing, such as presigned uploads or async writes lost to a restart.
+// It only reports by default; pass -dry-run=false to copy.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/config"
+	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", true, "Only report missing replicas, don't copy them")
+	prefix := flag.String("prefix", "", "Only check keys with this prefix")
+	flag.Parse()
+
+	cfg := config.Load()
+	// Copy inline so the command doesn't exit with work still queued
+	cfg.Storage.ReplicationMode = string(storage.ReplicateSync)
+
+	store, err := storage.NewProvider(context.Background(), cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	mirror := storage.FindMirror(store)
+	if mirror == nil {
+		log.Fatalf("No replicas configured, set STORAGE_REPLICAS")
+	}
+
+	report, err := mirror.Repair(context.Background(), *prefix, *dryRun)
+	if err != nil {
+		log.Fatalf("Repair failed: %v", err)
+	}
+
+	for name, missing := range report.Missing {
+		log.Printf("Replica %s: %d objects missing or out of date", name, missing)
+	}
+	log.Printf("Checked %d objects", report.Scanned)
+	if report.DryRun {
+		log.Printf("Dry run, nothing was copied. Re-run with -dry-run=false to repair")
+	} else {
+		log.Printf("Repaired %d copies, %d failed", report.Repaired, report.Failed)
+	}
+
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,91 @@
+// Command rotate-keys re-wraps every object's data key with the active
+// encryption key. Put the new key first in STORAGE_ENCRYPTION_KEYS, keep the
+// old ones listed, run this, and drop the old keys once it reports no failures.
+// Both tiers are rotated when a cold tier is configured, and so is the bucket
+// of every storage profile that has one, since they share the same keys.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/config"
+	"github.com/jaykapade/meeting-assistant/backend/internal/db"
+	"github.com/jaykapade/meeting-assistant/backend/internal/services"
+	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
+)
+
+func main() {
+	cfg := config.Load()
+	ctx := context.Background()
+
+	store, err := storage.NewProvider(ctx, cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	stores := []storage.Provider{store}
+	if tiered, ok := store.(*storage.TieredProvider); ok {
+		hot, cold := tiered.Tiers()
+		stores = []storage.Provider{hot, cold}
+	}
+
+	failed := 0
+	for _, tier := range stores {
+		encrypted, ok := tier.(*storage.EncryptedProvider)
+		if !ok {
+			log.Fatalf("Encryption is not enabled, set STORAGE_ENCRYPTION_KEYS or STORAGE_ENCRYPTION_KEY_FILE")
+		}
+		failed += rotate(ctx, "default store", encrypted)
+	}
+
+	// Profiles without a bucket live under a prefix of the default store,
+	// which was rotated above
+	dbConn, err := db.ConnectDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	profiles, err := services.NewStorageProfileService(dbConn, store, cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage profiles: %v", err)
+	}
+	profileIDs, err := profiles.BucketProfileIDs(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list storage profiles: %v", err)
+	}
+
+	for _, profileID := range profileIDs {
+		profileStore, err := profiles.ResolveProfile(ctx, profileID)
+		if err != nil {
+			// Its objects would be unreadable once the old keys are dropped
+			log.Printf("Failed to open the bucket of storage profile %d: %v", profileID, err)
+			failed++
+			continue
+		}
+		encrypted := storage.FindEncrypted(profileStore)
+		if encrypted == nil {
+			log.Printf("Storage profile %d is not encrypted", profileID)
+			failed++
+			continue
+		}
+		failed += rotate(ctx, fmt.Sprintf("storage profile %d", profileID), encrypted)
+	}
+
+	if failed > 0 {
+		log.Printf("%d data keys or buckets failed, keep the old keys until a re-run reports none", failed)
+		os.Exit(1)
+	}
+}
+
+// rotate re-wraps the data keys of one store and returns how many failed
+func rotate(ctx context.Context, name string, encrypted *storage.EncryptedProvider) int {
+	report, err := encrypted.RotateKeys(ctx)
+	if err != nil {
+		log.Printf("Key rotation of the %s failed: %v", name, err)
+		return 1
+	}
+	log.Printf("Checked %d data keys in the %s: %d re-wrapped, %d failed", report.Scanned, name, report.Rewrapped, report.Failed)
+	return report.Failed
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -33,41 +34,81 @@ func main() {
 
 	log.Printf("💾 Database connected successfully: %s", cfg.DB.Name)
 
+	if err := db.Migrate(dbConn); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
 	//3. Connect Storage
-	var store storage.Provider
 	ctx := context.Background()
+	defaultStore, err := storage.NewProvider(ctx, cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	// The local driver also serves its signed links itself
+	localStore, _ := storage.SigningProvider(defaultStore).(*storage.LocalDiskProvider)
 
-	// This switch allows us to change infrastructure just by changing an ENV var
-	switch cfg.Storage.Driver {
-	case "minio", "s3":
-		store, err = storage.NewS3Provider(
-			ctx,
-			cfg.Storage.Bucket,
-			cfg.Storage.Endpoint,
-			cfg.Storage.Region,
-			cfg.Storage.AccessKey,
-			cfg.Storage.SecretKey,
-		)
-		if err != nil {
-			log.Fatalf("Failed to initialize storage: %v", err)
-		}
-	case "local":
-		// You can implement a simple LocalDiskProvider later if needed
-		log.Fatal("Local storage driver not yet implemented")
-	default:
-		log.Fatalf("Unknown storage driver: %s", cfg.Storage.Driver)
+	// Users with a storage profile keep their recordings in their own bucket
+	profileService, err := services.NewStorageProfileService(dbConn, defaultStore, cfg.Storage)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage profiles: %v", err)
 	}
+	store := storage.NewRouter(defaultStore, profileService)
 
 	log.Printf("✅ Storage initialized: %s (Bucket: %s)", cfg.Storage.Driver, cfg.Storage.Bucket)
 
 	// 4. Register services and handlers
-	queueService := services.NewQueueService(cfg.Redis.URL)
-	meetingService := services.NewMeetingService(dbConn, store)
-	meetingHandler := handler.NewMeetingHandler(meetingService, queueService)
-	uploadHandler := handler.NewUploadHandler(store)
+	// The AI worker can't reach a queue held in this process's memory
+	if cfg.Redis.Backend == "memory" {
+		log.Fatalf("QUEUE_BACKEND=memory is only for tests, no worker could take its jobs; use redis or postgres")
+	}
+	queue, err := services.NewQueue(cfg.Redis, dbConn)
+	if err != nil {
+		log.Fatalf("Failed to initialize queue: %v", err)
+	}
+	// Processing progress and status changes, streamed to clients over SSE
+	meetingEvents := services.NewMeetingEventService(cfg.Redis.URL)
+	// Jobs are written to the outbox with the meeting change, and published by the relay
+	outboxRelay := services.NewOutboxRelay(dbConn, queue, cfg.Redis.OutboxInterval)
+	meetingService := services.NewMeetingService(dbConn, store, outboxRelay, meetingEvents)
+	retentionService := services.NewRetentionService(dbConn, store, cfg.Retention)
+	tieringService := services.NewTieringService(dbConn, storage.FindTiered(store), cfg.Storage.ColdAfter)
+	fileService := services.NewFileService(dbConn)
+	quotaService := services.NewQuotaService(dbConn, cfg.Quota.DefaultBytes)
+	transcriptService := services.NewTranscriptService(dbConn, store, cfg.Storage.TranscriptOffloadBytes)
+	jobService := services.NewJobService(dbConn)
+	meetingHandler := handler.NewMeetingHandler(meetingService, fileService, tieringService, transcriptService, jobService, meetingEvents)
+	uploadHandler := handler.NewUploadHandler(store, fileService, profileService, tieringService, quotaService, cfg.Storage.MaxUploadSize, cfg.Storage.SignedURLTTL)
+	uploadSessionService := services.NewUploadSessionService(cfg.Redis.URL, store)
+	tusHandler := handler.NewTusHandler(uploadSessionService, fileService, profileService, quotaService, cfg.Storage.MaxUploadSize)
 	routeCfg := &routes.RouteConfig{
-		MeetingHandler: meetingHandler,
-		UploadHandler:  uploadHandler,
+		MeetingHandler:    meetingHandler,
+		UploadHandler:     uploadHandler,
+		TusHandler:        tusHandler,
+		StorageHandler:    handler.NewStorageHandler(profileService, store, storage.FindMirror(store)),
+		RetentionHandler:  handler.NewRetentionHandler(retentionService, meetingService),
+		UsageHandler:      handler.NewUsageHandler(quotaService),
+		DeadLetterHandler: handler.NewDeadLetterHandler(services.NewDeadLetterService(dbConn, outboxRelay, meetingEvents)),
+		QueueHandler:      handler.NewQueueHandler(queue, cfg.Redis.Backend),
+		JobHandler:        handler.NewJobHandler(jobService),
+		AdminAuth:         handler.RequireToken(handler.AdminTokenHeader, cfg.Auth.AdminToken),
+		WorkerAuth:        handler.RequireToken(handler.WorkerTokenHeader, cfg.Auth.WorkerToken),
+	}
+	if localStore != nil {
+		routeCfg.LocalFileHandler = handler.NewLocalFileHandler(localStore)
+	}
+
+	// Finish deletions whose storage cleanup failed the first time
+	go meetingService.RunDeletionSweeper(ctx, time.Minute)
+	// Expire recordings and transcripts past their retention
+	go retentionService.RunSweeper(ctx, cfg.Retention.SweepInterval)
+	go outboxRelay.Run(ctx)
+	// Put back jobs of workers that died mid-job
+	go services.NewJobReaper(dbConn, queue, cfg.Redis.VisibilityTimeout, cfg.Redis.MaxAttempts, meetingEvents).RunReaper(ctx, cfg.Redis.ReapInterval)
+	// Move large transcripts written straight to the database into storage
+	go transcriptService.RunSweeper(ctx, 10*time.Minute)
+	if tieringService.Tiered != nil {
+		// Move recordings nobody plays any more to the cold tier
+		go tieringService.RunSweeper(ctx, cfg.Storage.TieringInterval)
 	}
 
 	// 5. Register routes
@@ -77,10 +118,20 @@ func main() {
 			"http://localhost:3000",
 			"http://127.0.0.1:3000",
 		},
-		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders: []string{
+			"Origin", "Content-Type", "Accept", "Authorization", "X-User-ID",
+			// tus resumable upload headers
+			"Tus-Resumable", "Upload-Length", "Upload-Metadata", "Upload-Offset",
+			// Seekable recording playback
+			"Range", "If-Range",
+			// Resuming the event stream
+			"Last-Event-ID",
+		},
 		ExposeHeaders: []string{
-			"Content-Length",
+			"Content-Length", "Content-Range", "Accept-Ranges", "ETag",
+			"Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size",
+			"Upload-Offset", "Upload-Length", "Upload-File-Id", "X-Storage-Tier",
 		},
 		AllowCredentials: true,
 	}))
@@ -0,0 +1,214 @@
+package audio
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("file is not a supported audio format")
+	ErrInvalidAudio      = errors.New("audio file is corrupt or truncated")
+)
+
+type Format string
+
+const (
+	FormatMP3 Format = "mp3"
+	FormatWAV Format = "wav"
+	FormatM4A Format = "m4a"
+)
+
+// MimeType is the canonical content type for a detected format
+func (f Format) MimeType() string {
+	switch f {
+	case FormatMP3:
+		return "audio/mpeg"
+	case FormatWAV:
+		return "audio/wav"
+	case FormatM4A:
+		return "audio/mp4"
+	}
+	return ""
+}
+
+// Metadata is what we can learn about a recording without decoding it
+type Metadata struct {
+	Format          Format  `json:"format"`
+	MimeType        string  `json:"mime_type"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	SampleRate      int     `json:"sample_rate"`
+	Channels        int     `json:"channels"`
+	Bitrate         int     `json:"bitrate"` // bits per second
+}
+
+// SniffSize is enough leading bytes for Detect to recognise every supported container
+const SniffSize = 12
+
+// Detect identifies the container from the first bytes of a file
+func Detect(head []byte) (Format, error) {
+	switch {
+	case len(head) >= 12 && string(head[0:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		return FormatWAV, nil
+	case len(head) >= 3 && string(head[0:3]) == "ID3":
+		return FormatMP3, nil
+	case len(head) >= 4 && validFrameHeader(head):
+		return FormatMP3, nil
+	case len(head) >= 8 && string(head[4:8]) == "ftyp":
+		return FormatM4A, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Analyzer parses audio metadata from a stream as it is written, so it can
+// sit next to an upload without buffering the file. Parsers ask for byte
+// ranges ahead of the current position and are called back once the range
+// has streamed past; everything else is discarded.
+type Analyzer struct {
+	total int64
+	err   error
+
+	format Format
+	meta   Metadata
+	parsed bool
+
+	// The single outstanding range request
+	want      int64
+	length    int
+	buf       []byte
+	next      func(data []byte) error
+	partialOK bool // Deliver a short range at EOF instead of failing
+
+	// Runs at EOF for values that depend on the total size
+	finish func(total int64) error
+}
+
+func NewAnalyzer() *Analyzer {
+	a := &Analyzer{}
+	a.request(0, SniffSize, a.sniff)
+	return a
+}
+
+// Analyze reads r to the end and returns its metadata, for a file that is
+// already stored
+func Analyze(r io.Reader) (*Metadata, error) {
+	a := NewAnalyzer()
+	if _, err := io.Copy(io.Discard, a.Reader(r)); err != nil {
+		return nil, err
+	}
+	return a.Result()
+}
+
+// Reader tees r into the analyzer and fails the read as soon as the data
+// is known not to be audio, so the upload stops early.
+func (a *Analyzer) Reader(r io.Reader) io.Reader {
+	return &analyzerReader{a: a, r: r}
+}
+
+func (a *Analyzer) Write(p []byte) (int, error) {
+	start := a.total
+	a.total += int64(len(p))
+
+	for a.err == nil && a.next != nil {
+		from := a.want + int64(len(a.buf))
+		if from >= a.total {
+			break
+		}
+		if from < start {
+			a.err = ErrInvalidAudio
+			break
+		}
+
+		n := min(int64(a.length-len(a.buf)), a.total-from)
+		a.buf = append(a.buf, p[from-start:from-start+n]...)
+		if len(a.buf) == a.length {
+			a.deliver()
+		}
+	}
+	return len(p), nil
+}
+
+// Result returns the metadata once the whole stream has been written
+func (a *Analyzer) Result() (*Metadata, error) {
+	if a.err == nil && a.next != nil {
+		if !a.partialOK {
+			return nil, a.fail()
+		}
+		a.deliver()
+	}
+	if a.err != nil {
+		return nil, a.err
+	}
+	if a.finish != nil {
+		if err := a.finish(a.total); err != nil {
+			return nil, err
+		}
+	}
+	if !a.parsed {
+		return nil, ErrInvalidAudio
+	}
+
+	a.meta.Format = a.format
+	a.meta.MimeType = a.format.MimeType()
+	return &a.meta, nil
+}
+
+func (a *Analyzer) request(offset int64, length int, next func(data []byte) error) {
+	a.want = offset
+	a.length = length
+	a.buf = make([]byte, 0, length)
+	a.next = next
+	a.partialOK = false
+}
+
+func (a *Analyzer) deliver() {
+	next, data := a.next, a.buf
+	a.next, a.buf = nil, nil
+	if err := next(data); err != nil {
+		a.err = err
+	}
+}
+
+// fail reports a stream that ended before the parser had what it needed
+func (a *Analyzer) fail() error {
+	if a.format == "" {
+		a.err = ErrUnsupportedFormat
+	} else {
+		a.err = ErrInvalidAudio
+	}
+	return a.err
+}
+
+func (a *Analyzer) sniff(head []byte) error {
+	format, err := Detect(head)
+	if err != nil {
+		return err
+	}
+	a.format = format
+
+	switch format {
+	case FormatWAV:
+		a.request(12, 8, a.wavChunk(12))
+	case FormatMP3:
+		a.mp3Start(head)
+	case FormatM4A:
+		// The first box header is already in hand
+		return a.mp4Box(0)(head[:8])
+	}
+	return nil
+}
+
+type analyzerReader struct {
+	a *Analyzer
+	r io.Reader
+}
+
+func (r *analyzerReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.a.Write(p[:n])
+	}
+	if r.a.err != nil {
+		return n, r.a.err
+	}
+	return n, err
+}
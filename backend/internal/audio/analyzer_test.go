@@ -0,0 +1,135 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"testing/iotest"
+)
+
+// analyze runs data through both the streaming and one-shot paths and checks
+// they agree, so parsers are exercised with ranges split across writes
+func analyze(t *testing.T, data []byte) (*Metadata, error) {
+	t.Helper()
+
+	a := NewAnalyzer()
+	for chunk := range chunks(data, 7) {
+		a.Write(chunk)
+	}
+	streamed, streamErr := a.Result()
+
+	metadata, err := Analyze(iotest.HalfReader(bytes.NewReader(data)))
+	if !errors.Is(err, streamErr) {
+		t.Fatalf("Analyze returned %v, streamed writes returned %v", err, streamErr)
+	}
+	if err == nil && *metadata != *streamed {
+		t.Fatalf("Analyze returned %+v, streamed writes returned %+v", metadata, streamed)
+	}
+	return metadata, err
+}
+
+func chunks(data []byte, size int) func(yield func([]byte) bool) {
+	return func(yield func([]byte) bool) {
+		for len(data) > 0 {
+			n := min(size, len(data))
+			if !yield(data[:n]) {
+				return
+			}
+			data = data[n:]
+		}
+	}
+}
+
+type analyzeTest struct {
+	name string
+	data []byte
+	want *Metadata
+	err  error
+}
+
+func runAnalyzeTests(t *testing.T, tests []analyzeTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := analyze(t, tt.data)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got error %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.Format != tt.want.Format || got.MimeType != tt.want.Format.MimeType() {
+				t.Errorf("format = %q (%s), want %q", got.Format, got.MimeType, tt.want.Format)
+			}
+			if got.SampleRate != tt.want.SampleRate || got.Channels != tt.want.Channels {
+				t.Errorf("sample rate/channels = %d/%d, want %d/%d", got.SampleRate, got.Channels, tt.want.SampleRate, tt.want.Channels)
+			}
+			if got.Bitrate != tt.want.Bitrate {
+				t.Errorf("bitrate = %d, want %d", got.Bitrate, tt.want.Bitrate)
+			}
+			if math.Abs(got.DurationSeconds-tt.want.DurationSeconds) > 1e-6 {
+				t.Errorf("duration = %f, want %f", got.DurationSeconds, tt.want.DurationSeconds)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want Format
+		err  error
+	}{
+		{"wav", []byte("RIFF\x24\x00\x00\x00WAVE"), FormatWAV, nil},
+		{"id3", []byte("ID3\x04\x00\x00\x00\x00\x00\x00\x00\x00"), FormatMP3, nil},
+		{"mp3 frame", []byte{0xFF, 0xFB, 0x90, 0x00}, FormatMP3, nil},
+		{"m4a", []byte("\x00\x00\x00\x20ftypM4A "), FormatM4A, nil},
+		{"riff without wave", []byte("RIFF\x24\x00\x00\x00AVI "), "", ErrUnsupportedFormat},
+		{"bad mp3 bitrate", []byte{0xFF, 0xFB, 0xF0, 0x00}, "", ErrUnsupportedFormat},
+		{"html", []byte("<!DOCTYPE html>"), "", ErrUnsupportedFormat},
+		{"empty", nil, "", ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Detect(tt.head)
+			if got != tt.want || !errors.Is(err, tt.err) {
+				t.Fatalf("Detect = %q, %v; want %q, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestAnalyzeRejectsUnknownContent(t *testing.T) {
+	runAnalyzeTests(t, []analyzeTest{
+		{name: "text", data: []byte("this is not a recording at all"), err: ErrUnsupportedFormat},
+		{name: "shorter than the sniff", data: []byte("ID"), err: ErrUnsupportedFormat},
+		{name: "empty", data: nil, err: ErrUnsupportedFormat},
+	})
+}
+
+func TestAnalyzerReaderStopsOnInvalidContent(t *testing.T) {
+	data := append([]byte("<html>"), make([]byte, 1<<20)...)
+	r := NewAnalyzer().Reader(bytes.NewReader(data))
+
+	buf := make([]byte, 32<<10)
+	read := 0
+	for {
+		n, err := r.Read(buf)
+		read += n
+		if err != nil {
+			if !errors.Is(err, ErrUnsupportedFormat) {
+				t.Fatalf("got error %v, want %v", err, ErrUnsupportedFormat)
+			}
+			break
+		}
+	}
+	if read >= len(data) {
+		t.Fatalf("read all %d bytes before failing", read)
+	}
+}
@@ -0,0 +1,169 @@
+package audio
+
+import "encoding/binary"
+
+// mp3Window is how far past the tag we look for the first frame
+const mp3Window = 64 << 10
+
+var (
+	// Kbps by [MPEG-1][layer] and [MPEG-2/2.5][layer], indexed by the 4-bit field
+	mpeg1Bitrates = [3][16]int{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0}, // Layer I
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},    // Layer II
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},     // Layer III
+	}
+	mpeg2Bitrates = [3][16]int{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0}, // Layer I
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},      // Layer II
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},      // Layer III
+	}
+	// Hz by version field (0 = MPEG-2.5, 2 = MPEG-2, 3 = MPEG-1)
+	sampleRates = [4][3]int{
+		{11025, 12000, 8000},
+		{0, 0, 0},
+		{22050, 24000, 16000},
+		{44100, 48000, 32000},
+	}
+)
+
+type mp3Frame struct {
+	mpeg1           bool
+	layer           int // 1, 2 or 3
+	bitrate         int // bits per second
+	sampleRate      int
+	channels        int
+	samplesPerFrame int
+	length          int
+}
+
+func parseFrameHeader(b []byte) (mp3Frame, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+
+	version := int(b[1]>>3) & 3
+	layerBits := int(b[1]>>1) & 3
+	bitrateIdx := int(b[2] >> 4)
+	rateIdx := int(b[2]>>2) & 3
+	padding := int(b[2]>>1) & 1
+	if version == 1 || layerBits == 0 || bitrateIdx == 0 || bitrateIdx == 15 || rateIdx == 3 {
+		return mp3Frame{}, false
+	}
+
+	f := mp3Frame{mpeg1: version == 3, layer: 4 - layerBits, channels: 2}
+	if b[3]>>6 == 3 {
+		f.channels = 1
+	}
+	if f.mpeg1 {
+		f.bitrate = mpeg1Bitrates[f.layer-1][bitrateIdx] * 1000
+	} else {
+		f.bitrate = mpeg2Bitrates[f.layer-1][bitrateIdx] * 1000
+	}
+	f.sampleRate = sampleRates[version][rateIdx]
+
+	switch {
+	case f.layer == 1:
+		f.samplesPerFrame = 384
+		f.length = (12*f.bitrate/f.sampleRate + padding) * 4
+	case f.layer == 3 && !f.mpeg1:
+		f.samplesPerFrame = 576
+		f.length = 72*f.bitrate/f.sampleRate + padding
+	default:
+		f.samplesPerFrame = 1152
+		f.length = 144*f.bitrate/f.sampleRate + padding
+	}
+	return f, true
+}
+
+func validFrameHeader(b []byte) bool {
+	_, ok := parseFrameHeader(b)
+	return ok
+}
+
+func (a *Analyzer) mp3Start(head []byte) {
+	if string(head[0:3]) != "ID3" {
+		a.request(SniffSize, mp3Window-SniffSize, func(data []byte) error {
+			return a.mp3Frames(0, append(head, data...))
+		})
+		a.partialOK = true
+		return
+	}
+
+	// ID3v2 size is a 28-bit "synchsafe" integer, plus an optional footer
+	size := int64(head[6]&0x7F)<<21 | int64(head[7]&0x7F)<<14 | int64(head[8]&0x7F)<<7 | int64(head[9]&0x7F)
+	frameStart := 10 + size
+	if head[5]&0x10 != 0 {
+		frameStart += 10
+	}
+
+	a.request(frameStart, mp3Window, func(data []byte) error {
+		return a.mp3Frames(frameStart, data)
+	})
+	a.partialOK = true
+}
+
+// mp3Frames finds the first real frame in window and reads the VBR header if any
+func (a *Analyzer) mp3Frames(base int64, window []byte) error {
+	for i := 0; i+4 <= len(window); i++ {
+		f, ok := parseFrameHeader(window[i:])
+		if !ok {
+			continue
+		}
+		// A lone sync pattern is common in tag data, so require a second frame
+		if next := i + f.length; next+4 <= len(window) && !validFrameHeader(window[next:]) {
+			continue
+		}
+
+		a.meta.SampleRate = f.sampleRate
+		a.meta.Channels = f.channels
+		audioStart := base + int64(i)
+
+		if frames := vbrFrameCount(window[i:], f); frames > 0 {
+			a.meta.DurationSeconds = float64(frames) * float64(f.samplesPerFrame) / float64(f.sampleRate)
+			a.finish = func(total int64) error {
+				a.meta.Bitrate = int(float64((total-audioStart)*8) / a.meta.DurationSeconds)
+				a.parsed = true
+				return nil
+			}
+			return nil
+		}
+
+		// Constant bitrate: every frame is the same size
+		a.meta.Bitrate = f.bitrate
+		a.finish = func(total int64) error {
+			a.meta.DurationSeconds = float64((total-audioStart)*8) / float64(f.bitrate)
+			a.parsed = true
+			return nil
+		}
+		return nil
+	}
+
+	return ErrInvalidAudio
+}
+
+// vbrFrameCount reads the Xing/Info or VBRI header stored in the first frame
+func vbrFrameCount(frame []byte, f mp3Frame) uint32 {
+	// The Xing header sits right after the side information
+	sideInfo := 32
+	switch {
+	case f.mpeg1 && f.channels == 1:
+		sideInfo = 17
+	case !f.mpeg1 && f.channels == 2:
+		sideInfo = 17
+	case !f.mpeg1:
+		sideInfo = 9
+	}
+
+	if x := 4 + sideInfo; len(frame) >= x+12 {
+		tag := string(frame[x : x+4])
+		flags := binary.BigEndian.Uint32(frame[x+4 : x+8])
+		if (tag == "Xing" || tag == "Info") && flags&1 != 0 {
+			return binary.BigEndian.Uint32(frame[x+8 : x+12])
+		}
+	}
+
+	if len(frame) >= 36+18 && string(frame[36:40]) == "VBRI" {
+		return binary.BigEndian.Uint32(frame[36+14 : 36+18])
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+package audio
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// MPEG-1 Layer III, 128 kbps, 44.1 kHz: 417 bytes per frame
+var (
+	stereoFrameHeader = []byte{0xFF, 0xFB, 0x90, 0x00}
+	monoFrameHeader   = []byte{0xFF, 0xFB, 0x90, 0xC0}
+)
+
+const frameLength = 417
+
+func mp3Frames(header []byte, count int) []byte {
+	var data []byte
+	for range count {
+		frame := make([]byte, frameLength)
+		copy(frame, header)
+		data = append(data, frame...)
+	}
+	return data
+}
+
+// id3Tag builds an ID3v2.4 tag with a synchsafe size
+func id3Tag(size int, footer bool) []byte {
+	tag := []byte{'I', 'D', '3', 4, 0, 0,
+		byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}
+	if footer {
+		tag[5] = 0x10
+		size += 10
+	}
+	body := make([]byte, size)
+	// A sync pattern in the tag must not be taken for audio
+	copy(body[100:], stereoFrameHeader)
+	return append(tag, body...)
+}
+
+// xingFrames starts with a Xing header declaring frames, at the offset the
+// side information puts it for the channel mode
+func xingFrames(header []byte, sideInfo int, frames uint32, count int) []byte {
+	data := mp3Frames(header, count)
+	at := 4 + sideInfo
+	copy(data[at:], "Xing")
+	binary.BigEndian.PutUint32(data[at+4:], 1) // Frame count present
+	binary.BigEndian.PutUint32(data[at+8:], frames)
+	return data
+}
+
+func TestAnalyzeMP3(t *testing.T) {
+	const cbrDuration = 100 * frameLength * 8 / 128000.0
+	vbrDuration := 500 * 1152 / 44100.0
+
+	withID3 := append(id3Tag(1000, false), mp3Frames(stereoFrameHeader, 100)...)
+	withFooter := append(id3Tag(1000, true), mp3Frames(stereoFrameHeader, 100)...)
+	vbr := append(id3Tag(200, false), xingFrames(stereoFrameHeader, 32, 500, 20)...)
+	vbrMono := xingFrames(monoFrameHeader, 17, 500, 20)
+
+	runAnalyzeTests(t, []analyzeTest{
+		{
+			name: "cbr",
+			data: mp3Frames(stereoFrameHeader, 100),
+			want: &Metadata{Format: FormatMP3, SampleRate: 44100, Channels: 2, Bitrate: 128000, DurationSeconds: cbrDuration},
+		},
+		{
+			name: "id3 prefixed",
+			data: withID3,
+			want: &Metadata{Format: FormatMP3, SampleRate: 44100, Channels: 2, Bitrate: 128000, DurationSeconds: cbrDuration},
+		},
+		{
+			name: "id3 with footer",
+			data: withFooter,
+			want: &Metadata{Format: FormatMP3, SampleRate: 44100, Channels: 2, Bitrate: 128000, DurationSeconds: cbrDuration},
+		},
+		{
+			name: "vbr xing",
+			data: vbr,
+			want: &Metadata{
+				Format: FormatMP3, SampleRate: 44100, Channels: 2,
+				Bitrate:         int(float64(20*frameLength*8) / vbrDuration),
+				DurationSeconds: vbrDuration,
+			},
+		},
+		{
+			name: "vbr xing mono",
+			data: vbrMono,
+			want: &Metadata{
+				Format: FormatMP3, SampleRate: 44100, Channels: 1,
+				Bitrate:         int(float64(20*frameLength*8) / vbrDuration),
+				DurationSeconds: vbrDuration,
+			},
+		},
+		{name: "id3 truncated inside the tag", data: id3Tag(1000, false)[:500], err: ErrInvalidAudio},
+		{name: "id3 with no frames", data: id3Tag(1000, false), err: ErrInvalidAudio},
+		{name: "lone sync pattern", data: append(append([]byte{}, stereoFrameHeader...), make([]byte, 2000)...), err: ErrInvalidAudio},
+	})
+}
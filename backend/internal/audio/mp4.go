@@ -0,0 +1,143 @@
+package audio
+
+import "encoding/binary"
+
+// moov is normally well under a megabyte; anything larger is not a recording
+const maxMoovSize = 32 << 20
+
+// mp4Box walks top-level boxes, skipping mdat, until it captures moov
+func (a *Analyzer) mp4Box(offset int64) func(data []byte) error {
+	return func(header []byte) error {
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		boxType := string(header[4:8])
+
+		if size == 1 {
+			// 64-bit size follows the type
+			a.request(offset+8, 8, func(large []byte) error {
+				return a.mp4Next(offset, boxType, int64(binary.BigEndian.Uint64(large)), 16)
+			})
+			return nil
+		}
+		if size == 0 {
+			// Box runs to EOF, so moov can't come after it
+			if boxType != "moov" {
+				return ErrInvalidAudio
+			}
+		}
+		return a.mp4Next(offset, boxType, size, 8)
+	}
+}
+
+func (a *Analyzer) mp4Next(offset int64, boxType string, size, headerSize int64) error {
+	if size < headerSize {
+		return ErrInvalidAudio
+	}
+
+	if boxType != "moov" {
+		next := offset + size
+		a.request(next, 8, a.mp4Box(next))
+		return nil
+	}
+
+	if size > maxMoovSize {
+		return ErrInvalidAudio
+	}
+	a.request(offset+headerSize, int(size-headerSize), func(moov []byte) error {
+		if err := a.parseMoov(moov); err != nil {
+			return err
+		}
+		a.finish = func(total int64) error {
+			if a.meta.DurationSeconds > 0 {
+				a.meta.Bitrate = int(float64(total*8) / a.meta.DurationSeconds)
+			}
+			return nil
+		}
+		a.parsed = true
+		return nil
+	})
+	return nil
+}
+
+func (a *Analyzer) parseMoov(moov []byte) error {
+	mvhd := findBox(moov, "mvhd")
+	if len(mvhd) < 4 {
+		return ErrInvalidAudio
+	}
+
+	var timescale, duration uint64
+	if mvhd[0] == 1 {
+		if len(mvhd) < 32 {
+			return ErrInvalidAudio
+		}
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[20:24]))
+		duration = binary.BigEndian.Uint64(mvhd[24:32])
+	} else {
+		if len(mvhd) < 20 {
+			return ErrInvalidAudio
+		}
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(mvhd[16:20]))
+	}
+	if timescale == 0 {
+		return ErrInvalidAudio
+	}
+	a.meta.DurationSeconds = float64(duration) / float64(timescale)
+
+	// Use the first sound track
+	for _, trak := range findBoxes(moov, "trak") {
+		mdia := findBox(trak, "mdia")
+		hdlr := findBox(mdia, "hdlr")
+		if len(hdlr) < 12 || string(hdlr[8:12]) != "soun" {
+			continue
+		}
+
+		stsd := findBox(findBox(findBox(mdia, "minf"), "stbl"), "stsd")
+		// version/flags (4) + entry count (4), then the first sample entry:
+		// size (4) + format (4) + reserved (6) + data ref (2) + reserved (8)
+		// + channels (2) + sample size (2) + reserved (4) + rate 16.16 (4)
+		if len(stsd) < 8+36 {
+			return ErrInvalidAudio
+		}
+		entry := stsd[8:]
+		a.meta.Channels = int(binary.BigEndian.Uint16(entry[24:26]))
+		a.meta.SampleRate = int(binary.BigEndian.Uint32(entry[32:36]) >> 16)
+		return nil
+	}
+
+	return ErrInvalidAudio
+}
+
+// findBox returns the payload of the first child box of the given type
+func findBox(data []byte, boxType string) []byte {
+	boxes := findBoxes(data, boxType)
+	if len(boxes) == 0 {
+		return nil
+	}
+	return boxes[0]
+}
+
+func findBoxes(data []byte, boxType string) [][]byte {
+	var boxes [][]byte
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		headerSize := uint64(8)
+		if size == 1 {
+			if len(data) < 16 {
+				break
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			headerSize = 16
+		} else if size == 0 {
+			size = uint64(len(data))
+		}
+		if size < headerSize || size > uint64(len(data)) {
+			break
+		}
+
+		if string(data[4:8]) == boxType {
+			boxes = append(boxes, data[headerSize:size])
+		}
+		data = data[size:]
+	}
+	return boxes
+}
@@ -0,0 +1,105 @@
+package audio
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func box(boxType string, payload ...[]byte) []byte {
+	b := make([]byte, 8)
+	copy(b[4:], boxType)
+	for _, p := range payload {
+		b = append(b, p...)
+	}
+	binary.BigEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
+
+// largeBox uses the 64-bit size form
+func largeBox(boxType string, payload []byte) []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint32(b, 1)
+	copy(b[4:], boxType)
+	binary.BigEndian.PutUint64(b[8:], uint64(16+len(payload)))
+	return append(b, payload...)
+}
+
+func mvhd(version byte, timescale uint32, duration uint64) []byte {
+	if version == 1 {
+		body := make([]byte, 112)
+		body[0] = 1
+		binary.BigEndian.PutUint32(body[20:], timescale)
+		binary.BigEndian.PutUint64(body[24:], duration)
+		return box("mvhd", body)
+	}
+	body := make([]byte, 100)
+	binary.BigEndian.PutUint32(body[12:], timescale)
+	binary.BigEndian.PutUint32(body[16:], uint32(duration))
+	return box("mvhd", body)
+}
+
+func trak(handler string, channels, sampleRate int) []byte {
+	hdlr := make([]byte, 25)
+	copy(hdlr[8:], handler)
+
+	entry := make([]byte, 36)
+	binary.BigEndian.PutUint32(entry[0:], 36)
+	copy(entry[4:], "mp4a")
+	binary.BigEndian.PutUint16(entry[24:], uint16(channels))
+	binary.BigEndian.PutUint16(entry[26:], 16)
+	binary.BigEndian.PutUint32(entry[32:], uint32(sampleRate)<<16)
+	stsd := append([]byte{0, 0, 0, 0, 0, 0, 0, 1}, entry...)
+
+	return box("trak", box("mdia",
+		box("hdlr", hdlr),
+		box("minf", box("stbl", box("stsd", stsd))),
+	))
+}
+
+var ftyp = box("ftyp", []byte("M4A \x00\x00\x00\x00M4A isom"))
+
+func TestAnalyzeMP4(t *testing.T) {
+	mdat := box("mdat", make([]byte, 100000))
+	moov := box("moov", mvhd(0, 1000, 90000), trak("soun", 2, 44100))
+
+	faststart := concat(ftyp, moov, mdat)
+	moovAtEnd := concat(ftyp, mdat, moov)
+	videoFirst := concat(ftyp, mdat, box("moov", mvhd(1, 48000, 48000*30), trak("vide", 0, 0), trak("soun", 1, 48000)))
+	large := concat(ftyp, largeBox("mdat", make([]byte, 5000)), moov)
+
+	runAnalyzeTests(t, []analyzeTest{
+		{
+			name: "moov first",
+			data: faststart,
+			want: &Metadata{Format: FormatM4A, SampleRate: 44100, Channels: 2, Bitrate: len(faststart) * 8 / 90, DurationSeconds: 90},
+		},
+		{
+			name: "moov at end",
+			data: moovAtEnd,
+			want: &Metadata{Format: FormatM4A, SampleRate: 44100, Channels: 2, Bitrate: len(moovAtEnd) * 8 / 90, DurationSeconds: 90},
+		},
+		{
+			name: "version 1 mvhd and a video track",
+			data: videoFirst,
+			want: &Metadata{Format: FormatM4A, SampleRate: 48000, Channels: 1, Bitrate: len(videoFirst) * 8 / 30, DurationSeconds: 30},
+		},
+		{
+			name: "64-bit mdat",
+			data: large,
+			want: &Metadata{Format: FormatM4A, SampleRate: 44100, Channels: 2, Bitrate: len(large) * 8 / 90, DurationSeconds: 90},
+		},
+		{name: "truncated mdat", data: moovAtEnd[:len(ftyp)+5000], err: ErrInvalidAudio},
+		{name: "truncated moov", data: moovAtEnd[:len(moovAtEnd)-20], err: ErrInvalidAudio},
+		{name: "no moov", data: concat(ftyp, mdat), err: ErrInvalidAudio},
+		{name: "no sound track", data: concat(ftyp, box("moov", mvhd(0, 1000, 1000), trak("vide", 0, 0))), err: ErrInvalidAudio},
+		{name: "zero timescale", data: concat(ftyp, box("moov", mvhd(0, 0, 1000), trak("soun", 2, 44100))), err: ErrInvalidAudio},
+	})
+}
+
+func concat(parts ...[]byte) []byte {
+	var data []byte
+	for _, p := range parts {
+		data = append(data, p...)
+	}
+	return data
+}
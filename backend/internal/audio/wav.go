@@ -0,0 +1,51 @@
+package audio
+
+import "encoding/binary"
+
+// wavChunk walks RIFF chunks until it has both "fmt " and the start of "data"
+func (a *Analyzer) wavChunk(offset int64) func(data []byte) error {
+	return func(header []byte) error {
+		id := string(header[0:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:8]))
+		body := offset + 8
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return ErrInvalidAudio
+			}
+			a.request(body, 16, func(fmtChunk []byte) error {
+				a.meta.Channels = int(binary.LittleEndian.Uint16(fmtChunk[2:4]))
+				a.meta.SampleRate = int(binary.LittleEndian.Uint32(fmtChunk[4:8]))
+				byteRate := int64(binary.LittleEndian.Uint32(fmtChunk[8:12]))
+				if a.meta.SampleRate == 0 || byteRate == 0 {
+					return ErrInvalidAudio
+				}
+				a.meta.Bitrate = int(byteRate * 8)
+
+				next := body + size + size&1 // Chunks are padded to even sizes
+				a.request(next, 8, a.wavChunk(next))
+				return nil
+			})
+		case "data":
+			if a.meta.Bitrate == 0 {
+				return ErrInvalidAudio // "fmt " must come first
+			}
+			// Streamed WAVs often leave the size as 0 or 0xFFFFFFFF, so
+			// trust the bytes we actually received when they disagree
+			a.finish = func(total int64) error {
+				dataSize := size
+				if dataSize == 0 || body+dataSize > total {
+					dataSize = total - body
+				}
+				a.meta.DurationSeconds = float64(dataSize) / float64(a.meta.Bitrate/8)
+				a.parsed = true
+				return nil
+			}
+		default:
+			next := body + size + size&1
+			a.request(next, 8, a.wavChunk(next))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+package audio
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func riffChunk(id string, body []byte) []byte {
+	chunk := make([]byte, 8, 8+len(body)+1)
+	copy(chunk, id)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(body)))
+	chunk = append(chunk, body...)
+	if len(body)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// pcmFormat is a "fmt " body for 16-bit PCM, padded to size bytes
+func pcmFormat(channels, sampleRate, size int) []byte {
+	body := make([]byte, size)
+	binary.LittleEndian.PutUint16(body[0:], 1)
+	binary.LittleEndian.PutUint16(body[2:], uint16(channels))
+	binary.LittleEndian.PutUint32(body[4:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(body[8:], uint32(sampleRate*channels*2))
+	binary.LittleEndian.PutUint16(body[12:], uint16(channels*2))
+	binary.LittleEndian.PutUint16(body[14:], 16)
+	return body
+}
+
+func wavFile(chunks ...[]byte) []byte {
+	var body []byte
+	for _, chunk := range chunks {
+		body = append(body, chunk...)
+	}
+	header := []byte("RIFF\x00\x00\x00\x00WAVE")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+len(body)))
+	return append(header, body...)
+}
+
+func TestAnalyzeWAV(t *testing.T) {
+	samples := make([]byte, 2*16000*2) // Two seconds of 16 kHz mono
+	stereo := make([]byte, 44100*2*2)  // One second of 44.1 kHz stereo
+
+	// A stream that never went back to fill in the data size
+	streamed := wavFile(riffChunk("fmt ", pcmFormat(1, 16000, 16)), riffChunk("data", samples))
+	binary.LittleEndian.PutUint32(streamed[40:], 0xFFFFFFFF)
+
+	runAnalyzeTests(t, []analyzeTest{
+		{
+			name: "plain",
+			data: wavFile(riffChunk("fmt ", pcmFormat(1, 16000, 16)), riffChunk("data", samples)),
+			want: &Metadata{Format: FormatWAV, SampleRate: 16000, Channels: 1, Bitrate: 256000, DurationSeconds: 2},
+		},
+		{
+			name: "extra chunks",
+			data: wavFile(
+				riffChunk("JUNK", make([]byte, 28)),
+				riffChunk("fmt ", pcmFormat(2, 44100, 18)),
+				riffChunk("LIST", []byte("INFOx")), // Odd size, padded
+				riffChunk("data", stereo),
+				riffChunk("id3 ", make([]byte, 64)),
+			),
+			want: &Metadata{Format: FormatWAV, SampleRate: 44100, Channels: 2, Bitrate: 1411200, DurationSeconds: 1},
+		},
+		{
+			name: "unknown data size",
+			data: streamed,
+			want: &Metadata{Format: FormatWAV, SampleRate: 16000, Channels: 1, Bitrate: 256000, DurationSeconds: 2},
+		},
+		{
+			name: "truncated data",
+			data: wavFile(riffChunk("fmt ", pcmFormat(1, 16000, 16)), riffChunk("data", samples))[:44+16000],
+			want: &Metadata{Format: FormatWAV, SampleRate: 16000, Channels: 1, Bitrate: 256000, DurationSeconds: 0.5},
+		},
+		{name: "truncated fmt", data: wavFile(riffChunk("fmt ", pcmFormat(1, 16000, 16)))[:30], err: ErrInvalidAudio},
+		{name: "no data chunk", data: wavFile(riffChunk("fmt ", pcmFormat(1, 16000, 16))), err: ErrInvalidAudio},
+		{name: "data before fmt", data: wavFile(riffChunk("data", samples), riffChunk("fmt ", pcmFormat(1, 16000, 16))), err: ErrInvalidAudio},
+		{name: "zero sample rate", data: wavFile(riffChunk("fmt ", pcmFormat(1, 0, 16)), riffChunk("data", samples)), err: ErrInvalidAudio},
+	})
+}
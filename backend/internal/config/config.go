@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -23,16 +26,102 @@ type StorageConfig struct {
 	Region    string
 	AccessKey string
 	SecretKey string
+	// How long signed download URLs stay valid
+	SignedURLTTL time.Duration
+	// Largest recording accepted by either upload endpoint, in bytes
+	MaxUploadSize int64
+	// Local driver settings
+	RootDir       string // Directory that holds objects when Driver is "local"
+	PublicURL     string // Base URL used to build signed local download links
+	SigningSecret string // HMAC key for signed local download links
+	// Envelope encryption at rest, enabled when either is set. Keys are
+	// "id:base64" entries separated by commas or newlines; the first one
+	// encrypts new objects and the rest are kept to read older ones.
+	EncryptionKeys    string
+	EncryptionKeyFile string
+	// Secondary copies of every object, named in STORAGE_REPLICAS
+	Replicas []ReplicaConfig
+	// "sync" waits for replicas before a write returns, "async" copies in the background
+	ReplicationMode string
+	// Keys that seal the bucket credentials of storage profiles, in the same
+	// "id:base64" format as EncryptionKeys
+	CredentialsKeys string
+	// Storage profile endpoints must use https and resolve to public addresses.
+	// Hosts listed in STORAGE_PROFILE_ALLOWED_HOSTS are exempt, e.g. a MinIO
+	// on the internal network.
+	ProfileAllowedHosts []string
+	// Refuse to connect to private addresses; set for user-supplied endpoints
+	PublicEndpointOnly bool
+	// Cold tier for old recordings, configured with STORAGE_COLD_* variables.
+	// Recordings not played for ColdAfter are moved there.
+	Cold            *ReplicaConfig
+	ColdAfter       time.Duration
+	TieringInterval time.Duration
+	// Transcripts larger than this are moved out of Postgres into storage
+	TranscriptOffloadBytes int64
+}
+
+// ReplicaConfig describes one secondary store. Each replica is configured with
+// STORAGE_REPLICA_<NAME>_* variables, e.g. STORAGE_REPLICA_DR_DRIVER=s3.
+type ReplicaConfig struct {
+	Name         string
+	Driver       string // "s3", "minio", or "local"
+	Bucket       string
+	Endpoint     string
+	Region       string
+	AccessKey    string
+	SecretKey    string
+	RootDir      string
+	StorageClass string // S3 storage class for new objects, e.g. STANDARD_IA
+}
+
+// RetentionConfig holds the global retention defaults. Workspaces and meetings
+// can override them; zero days keeps the artifact forever.
+type RetentionConfig struct {
+	RecordingDays  int
+	TranscriptDays int
+	// How often the sweeper looks for expired artifacts
+	SweepInterval time.Duration
+}
+
+// QuotaConfig limits how much each owner may store in the default bucket.
+// Individual owners can be given a different quota through the admin API.
+type QuotaConfig struct {
+	DefaultBytes int64 // 0 means unlimited
 }
 
 type QueueConfig struct {
-	URL string // e.g., "localhost:6379" or "redis:6379"
+	// "redis", "postgres" to keep jobs out of Redis, or "memory", which only
+	// in-process consumers can read and is meant for tests. Redis is still
+	// required with postgres: resumable upload sessions and meeting events,
+	// on both the backend and the worker, always use it.
+	Backend string
+	URL     string // e.g., "localhost:6379" or "redis:6379"
+	// A job goes back on the queue when its worker hasn't sent a heartbeat for this long
+	VisibilityTimeout time.Duration
+	// Deliveries before a job fails for good
+	MaxAttempts int
+	// How often the reaper looks for jobs of dead workers
+	ReapInterval time.Duration
+	// How often the outbox relay looks for jobs it couldn't publish right away
+	OutboxInterval time.Duration
+}
+
+// AuthConfig holds the shared secrets for endpoints that only operators or
+// the AI worker may call. Those endpoints are disabled while their secret is
+// empty.
+type AuthConfig struct {
+	AdminToken  string
+	WorkerToken string // Must match WORKER_TOKEN of the AI service
 }
 
 type Config struct {
 	DB         DBConfig
 	Storage    StorageConfig
+	Retention  RetentionConfig
+	Quota      QuotaConfig
 	Redis      QueueConfig
+	Auth       AuthConfig
 	ServerPort string
 }
 
@@ -60,14 +149,98 @@ func Load() *Config {
 			Region:    getEnv("STORAGE_REGION", "us-east-1"),
 			AccessKey: getEnv("STORAGE_ACCESS_KEY", "minioadmin"),
 			SecretKey: getEnv("STORAGE_SECRET_KEY", "minioadmin"),
+
+			SignedURLTTL:  getEnvDuration("STORAGE_SIGNED_URL_TTL", 15*time.Minute),
+			MaxUploadSize: getEnvInt64("UPLOAD_MAX_SIZE_MB", 50) << 20, // Default to 50 MB
+
+			RootDir:       getEnv("STORAGE_ROOT_DIR", "../uploads"), // Matches the AI service uploads dir
+			PublicURL:     getEnv("STORAGE_PUBLIC_URL", "http://localhost:8080"),
+			SigningSecret: getEnv("STORAGE_SIGNING_SECRET", ""),
+
+			EncryptionKeys:    getEnv("STORAGE_ENCRYPTION_KEYS", ""),
+			EncryptionKeyFile: getEnv("STORAGE_ENCRYPTION_KEY_FILE", ""),
+
+			Replicas:        loadReplicas(getEnv("STORAGE_REPLICAS", "")),
+			ReplicationMode: getEnv("STORAGE_REPLICATION_MODE", "async"),
+
+			CredentialsKeys:     getEnv("STORAGE_CREDENTIALS_KEYS", ""),
+			ProfileAllowedHosts: splitList(getEnv("STORAGE_PROFILE_ALLOWED_HOSTS", "")),
+
+			Cold:            loadColdTier(),
+			ColdAfter:       getEnvDuration("STORAGE_COLD_AFTER", 7*24*time.Hour),
+			TieringInterval: getEnvDuration("STORAGE_TIERING_INTERVAL", time.Hour),
+
+			TranscriptOffloadBytes: getEnvInt64("TRANSCRIPT_OFFLOAD_KB", 256) << 10, // Default to 256 KB
+		},
+		Retention: RetentionConfig{
+			RecordingDays:  int(getEnvInt64("RETENTION_RECORDING_DAYS", 0)),
+			TranscriptDays: int(getEnvInt64("RETENTION_TRANSCRIPT_DAYS", 0)),
+			SweepInterval:  getEnvDuration("RETENTION_SWEEP_INTERVAL", time.Hour),
+		},
+		Quota: QuotaConfig{
+			DefaultBytes: getEnvInt64("QUOTA_DEFAULT_MB", 0) << 20,
 		},
 		Redis: QueueConfig{
-			URL: getEnv("REDIS_URL", "localhost:6379"), // Default to localhost for dev
+			Backend: getEnv("QUEUE_BACKEND", "redis"),
+			URL:     getEnv("REDIS_URL", "localhost:6379"), // Default to localhost for dev
+
+			VisibilityTimeout: getEnvDuration("QUEUE_VISIBILITY_TIMEOUT", 2*time.Minute),
+			MaxAttempts:       int(getEnvInt64("QUEUE_MAX_ATTEMPTS", 3)),
+			ReapInterval:      getEnvDuration("QUEUE_REAP_INTERVAL", 30*time.Second),
+			OutboxInterval:    getEnvDuration("QUEUE_OUTBOX_INTERVAL", 5*time.Second),
+		},
+		Auth: AuthConfig{
+			AdminToken:  getEnv("ADMIN_TOKEN", ""),
+			WorkerToken: getEnv("WORKER_TOKEN", ""),
 		},
 		ServerPort: getEnv("SERVER_PORT", "8080"), // Default to 8080
 	}
 }
 
+// loadReplicas reads the settings of each replica in a comma separated list of names
+func loadReplicas(names string) []ReplicaConfig {
+	var replicas []ReplicaConfig
+	for _, name := range splitList(names) {
+		replicas = append(replicas, loadStore(name, "STORAGE_REPLICA_"+strings.ToUpper(name)+"_"))
+	}
+	return replicas
+}
+
+// splitList splits a comma separated value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loadColdTier reads the cold tier settings, or returns nil if STORAGE_COLD_DRIVER is unset
+func loadColdTier() *ReplicaConfig {
+	if getEnv("STORAGE_COLD_DRIVER", "") == "" {
+		return nil
+	}
+	cold := loadStore("cold", "STORAGE_COLD_")
+	return &cold
+}
+
+// loadStore reads the settings of a secondary store from variables starting with prefix
+func loadStore(name, prefix string) ReplicaConfig {
+	return ReplicaConfig{
+		Name:         name,
+		Driver:       getEnv(prefix+"DRIVER", "s3"),
+		Bucket:       getEnv(prefix+"BUCKET", ""),
+		Endpoint:     getEnv(prefix+"ENDPOINT", ""),
+		Region:       getEnv(prefix+"REGION", "us-east-1"),
+		AccessKey:    getEnv(prefix+"ACCESS_KEY", ""),
+		SecretKey:    getEnv(prefix+"SECRET_KEY", ""),
+		RootDir:      getEnv(prefix+"ROOT_DIR", ""),
+		StorageClass: getEnv(prefix+"STORAGE_CLASS", ""),
+	}
+}
+
 // Helper to read env with a default fallback
 func getEnv(key, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -75,3 +248,31 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// Helper to read a duration like "15m" or "1h", falling back on bad input
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid duration for %s: %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// Helper to read a positive integer, falling back on bad input
+func getEnvInt64(key string, fallback int64) int64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		log.Printf("Invalid number for %s: %q, using %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
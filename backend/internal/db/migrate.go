@@ -0,0 +1,60 @@
+package db
+
+import (
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// meetingColumns were added after the meetings table was first created.
+// The table is shared with the AI service, so we only add what is missing
+// instead of letting AutoMigrate rewrite existing column types.
+var meetingColumns = []string{
+	"RecordingFileID",
+	"RecordingMimeType",
+	"RecordingSampleRate",
+	"RecordingChannels",
+	"RecordingBitrate",
+	"LegalHold",
+	"RecordingRetentionDays",
+	"TranscriptRetentionDays",
+	"RecordingExpiredAt",
+	"TranscriptExpiredAt",
+	"TranscriptKey",
+	"TranscriptSizeBytes",
+	"FailureReason",
+}
+
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(
+		&models.File{},
+		&models.FileGrant{},
+		&models.StorageProfile{},
+		&models.RetentionPolicy{},
+		&models.RetentionAudit{},
+		&models.StorageUsage{},
+		&models.DeadLetter{},
+		&models.OutboxMessage{},
+		&models.QueueJob{},
+		&models.Job{},
+	); err != nil {
+		return err
+	}
+
+	// Checksums used to be unique across all files, before storage profiles
+	if db.Migrator().HasIndex(&models.File{}, "idx_files_checksum") {
+		if err := db.Migrator().DropIndex(&models.File{}, "idx_files_checksum"); err != nil {
+			return err
+		}
+	}
+
+	for _, column := range meetingColumns {
+		if db.Migrator().HasColumn(&models.Meeting{}, column) {
+			continue
+		}
+		if err := db.Migrator().AddColumn(&models.Meeting{}, column); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
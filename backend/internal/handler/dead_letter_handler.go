@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDeadLetterPage = 50
+	maxDeadLetterPage     = 500
+)
+
+type ReplayDeadLettersRequest struct {
+	// Omitted or empty replays every dead letter that hasn't been replayed yet
+	IDs []uint `json:"ids"`
+}
+
+type DeadLetterHandler struct {
+	DeadLetters *services.DeadLetterService
+}
+
+func NewDeadLetterHandler(deadLetters *services.DeadLetterService) *DeadLetterHandler {
+	return &DeadLetterHandler{DeadLetters: deadLetters}
+}
+
+// ListDeadLetters pages through failed jobs, newest first. It accepts
+// meeting_id, replayed, before (RFC 3339), limit and offset.
+func (h *DeadLetterHandler) ListDeadLetters(c *gin.Context) {
+	filter, ok := deadLetterFilter(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultDeadLetterPage)))
+	if err != nil || limit <= 0 || limit > maxDeadLetterPage {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return
+	}
+
+	letters, total, err := h.DeadLetters.List(filter, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dead_letters": letters, "total": total})
+}
+
+// GetDeadLetter shows one failed job with its payload and stack trace
+func (h *DeadLetterHandler) GetDeadLetter(c *gin.Context) {
+	id, ok := deadLetterID(c)
+	if !ok {
+		return
+	}
+
+	letter, err := h.DeadLetters.Get(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dead letter not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, letter)
+}
+
+// ReplayDeadLetter queues the meeting of one failed job again
+func (h *DeadLetterHandler) ReplayDeadLetter(c *gin.Context) {
+	id, ok := deadLetterID(c)
+	if !ok {
+		return
+	}
+
+	letter, err := h.DeadLetters.Replay(c.Request.Context(), id)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, letter)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dead letter not found"})
+	case errors.Is(err, services.ErrDeadLetterReplayed), errors.Is(err, services.ErrNotReplayable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// ReplayDeadLetters replays the listed dead letters, or all pending ones, and
+// reports the outcome of each
+func (h *DeadLetterHandler) ReplayDeadLetters(c *gin.Context) {
+	var req ReplayDeadLettersRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	results, err := h.DeadLetters.ReplayMany(c.Request.Context(), req.IDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	replayed := 0
+	for _, result := range results {
+		if result.Replayed {
+			replayed++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"replayed": replayed, "results": results})
+}
+
+func (h *DeadLetterHandler) DeleteDeadLetter(c *gin.Context) {
+	id, ok := deadLetterID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DeadLetters.Delete(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dead letter not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Dead letter deleted"})
+}
+
+// PurgeDeadLetters deletes the dead letters matching the same filters as the
+// list. Purging everything has to be asked for with all=true.
+func (h *DeadLetterHandler) PurgeDeadLetters(c *gin.Context) {
+	filter, ok := deadLetterFilter(c)
+	if !ok {
+		return
+	}
+	if filter == (services.DeadLetterFilter{}) && c.Query("all") != "true" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Pass a filter, or all=true to purge every dead letter"})
+		return
+	}
+
+	purged, err := h.DeadLetters.Purge(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
+
+func deadLetterID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// deadLetterFilter reads the query filters shared by list and purge
+func deadLetterFilter(c *gin.Context) (services.DeadLetterFilter, bool) {
+	var filter services.DeadLetterFilter
+
+	if value := c.Query("meeting_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meeting_id"})
+			return filter, false
+		}
+		meetingID := uint(id)
+		filter.MeetingID = &meetingID
+	}
+	if value := c.Query("replayed"); value != "" {
+		replayed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid replayed"})
+			return filter, false
+		}
+		filter.Replayed = &replayed
+	}
+	if value := c.Query("before"); value != "" {
+		before, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before, expected RFC 3339"})
+			return filter, false
+		}
+		filter.Before = &before
+	}
+	return filter, true
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"github.com/jaykapade/meeting-assistant/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+type StartJobRequest struct {
+	// The job as the worker took it off the queue
+	Job      services.MeetingJob `json:"job"`
+	WorkerID string              `json:"worker_id" binding:"required"`
+}
+
+type RecordJobStageRequest struct {
+	Stage      string    `json:"stage" binding:"required,max=50"`
+	StartedAt  time.Time `json:"started_at" binding:"required"`
+	DurationMs int64     `json:"duration_ms" binding:"min=0"`
+}
+
+type FinishJobRequest struct {
+	State string  `json:"state" binding:"required,oneof=succeeded failed"`
+	Error *string `json:"error"`
+	// The failed attempt goes back on the queue for another try
+	Retry bool `json:"retry"`
+}
+
+// JobHandler is the API the worker reports its progress through
+type JobHandler struct {
+	Jobs *services.JobService
+}
+
+func NewJobHandler(jobs *services.JobService) *JobHandler {
+	return &JobHandler{Jobs: jobs}
+}
+
+// StartJob records that a worker took a job, and returns the attempt it is on
+func (h *JobHandler) StartJob(c *gin.Context) {
+	var req StartJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Job.ID == 0 || req.Job.JobID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Job needs an id and a job_id"})
+		return
+	}
+
+	job, err := h.Jobs.Start(req.Job, req.WorkerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, job)
+}
+
+// RecordJobStage adds the timing of a stage the worker finished
+func (h *JobHandler) RecordJobStage(c *gin.Context) {
+	id, ok := jobID(c)
+	if !ok {
+		return
+	}
+	var req RecordJobStageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := h.Jobs.RecordStage(id, req.Stage, models.StageTiming{StartedAt: req.StartedAt, DurationMs: req.DurationMs})
+	h.respond(c, job, err)
+}
+
+// FinishJob records how an attempt ended
+func (h *JobHandler) FinishJob(c *gin.Context) {
+	id, ok := jobID(c)
+	if !ok {
+		return
+	}
+	var req FinishJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := h.Jobs.Finish(id, models.JobState(req.State), req.Error, req.Retry)
+	h.respond(c, job, err)
+}
+
+func (h *JobHandler) respond(c *gin.Context, job *models.Job, err error) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, job)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+	case errors.Is(err, services.ErrJobFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func jobID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return 0, false
+	}
+	return uint(id), true
+}
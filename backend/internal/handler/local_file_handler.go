@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
+)
+
+type LocalFileHandler struct {
+	Store *storage.LocalDiskProvider
+}
+
+func NewLocalFileHandler(store *storage.LocalDiskProvider) *LocalFileHandler {
+	return &LocalFileHandler{Store: store}
+}
+
+// ServeFile streams a file from local storage if the signed URL is valid
+func (h *LocalFileHandler) ServeFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	query := c.Request.URL.Query()
+
+	if err := h.Store.Verify(http.MethodGet, key, query); err != nil {
+		h.writeError(c, err)
+		return
+	}
+
+	file, err := h.Store.OpenFile(key)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	// The overrides were covered by the signature, so they are safe to apply
+	if filename := query.Get("filename"); filename != "" {
+		c.Header("Content-Disposition", storage.ContentDisposition(filename))
+	}
+	if contentType := query.Get("content_type"); contentType != "" {
+		c.Header("Content-Type", contentType)
+	}
+
+	// ServeContent handles Range requests and sniffs the content type from the name
+	http.ServeContent(c.Writer, c.Request, path.Base(key), info.ModTime(), file)
+}
+
+// ReceiveFile stores the body of a presigned PUT, mirroring what S3 enforces
+func (h *LocalFileHandler) ReceiveFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	query := c.Request.URL.Query()
+
+	if err := h.Store.Verify(http.MethodPut, key, query); err != nil {
+		h.writeError(c, err)
+		return
+	}
+
+	contentType := query.Get("content_type")
+	if c.GetHeader("Content-Type") != contentType {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Content-Type does not match the signed upload"})
+		return
+	}
+
+	size, _ := strconv.ParseInt(query.Get("size"), 10, 64)
+	if c.Request.ContentLength != size {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Content-Length does not match the signed upload"})
+		return
+	}
+
+	uploadCtx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	defer cancel()
+
+	result, err := h.Store.Upload(uploadCtx, io.LimitReader(c.Request.Body, size), key, contentType)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	if result.Size != size {
+		h.Store.Delete(context.Background(), key)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload ended before the signed size was reached"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *LocalFileHandler) writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrInvalidSignature):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Link is invalid or expired"})
+	case errors.Is(err, storage.ErrInvalidKey):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file key"})
+	case errors.Is(err, os.ErrNotExist):
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to access file"})
+	}
+}
@@ -1,14 +1,22 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"path"
 	"strconv"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jaykapade/meeting-assistant/backend/internal/models"
 	"github.com/jaykapade/meeting-assistant/backend/internal/services"
+	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
 	"gorm.io/gorm"
 )
 
@@ -16,7 +24,8 @@ type CreateMeetingRequest struct {
 	Title       string  `json:"title" binding:"required"`
 	Description string  `json:"description"`
 	MeetingURL  *string `json:"meeting_url"` // Pointer allows null
-	// Recording Details
+	// Recording Details: reference an uploaded file by ID, or by its key
+	RecordingFileID          *uint   `json:"recording_file_id"`
 	RecordingPath            *string `json:"recording_path"`
 	RecordingDurationSeconds *int    `json:"recording_duration_seconds"`
 	RecordingSizeBytes       *int64  `json:"recording_size_bytes"`
@@ -27,21 +36,39 @@ type UpdateMeetingRequest struct {
 	Description *string `json:"description"`
 	MeetingURL  *string `json:"meeting_url"`
 	// Recording Details
+	RecordingFileID          *uint   `json:"recording_file_id"`
 	RecordingPath            *string `json:"recording_path"`
 	RecordingDurationSeconds *int    `json:"recording_duration_seconds"`
 	RecordingSizeBytes       *int64  `json:"recording_size_bytes"`
 	Status                   *string `json:"status" binding:"omitempty,oneof=created processing completed failed"`
+	// Copy results from a finished meeting with the same recording instead of reprocessing
+	ReuseTranscript bool `json:"reuse_transcript"`
+}
+
+type AttachRecordingRequest struct {
+	FileID          string `json:"file_id" binding:"required"`
+	ReuseTranscript bool   `json:"reuse_transcript"`
 }
 
 type MeetingHandler struct {
 	MeetingService *services.MeetingService
-	QueueService   *services.QueueService
+	FileService    *services.FileService
+	Tiering        *services.TieringService
+	Transcripts    *services.TranscriptService
+	Jobs           *services.JobService
+	Events         *services.MeetingEventService
 }
 
-func NewMeetingHandler(ms *services.MeetingService, qs *services.QueueService) *MeetingHandler {
-	return &MeetingHandler{MeetingService: ms, QueueService: qs}
+func NewMeetingHandler(ms *services.MeetingService, fs *services.FileService, ts *services.TieringService, transcripts *services.TranscriptService, jobs *services.JobService, events *services.MeetingEventService) *MeetingHandler {
+	return &MeetingHandler{MeetingService: ms, FileService: fs, Tiering: ts, Transcripts: transcripts, Jobs: jobs, Events: events}
 }
 
+// maxTranscriptSize bounds the transcripts a worker can store
+const maxTranscriptSize = 64 << 20
+
+// How often the event stream sends a comment to keep proxies from closing it
+const eventStreamHeartbeat = 15 * time.Second
+
 func (h *MeetingHandler) CreateMeeting(c *gin.Context) {
 	var req CreateMeetingRequest
 
@@ -54,9 +81,19 @@ func (h *MeetingHandler) CreateMeeting(c *gin.Context) {
 		Title:                    req.Title,
 		Description:              req.Description,
 		MeetingURL:               req.MeetingURL,
-		RecordingPath:            req.RecordingPath,
 		RecordingDurationSeconds: req.RecordingDurationSeconds,
 		RecordingSizeBytes:       req.RecordingSizeBytes,
+		UserID:                   requestUserID(c),
+	}
+
+	// Only files uploaded through the API can be attached
+	if req.RecordingFileID != nil || req.RecordingPath != nil {
+		file, ok := h.resolveRecording(c, req.RecordingFileID, req.RecordingPath)
+		if !ok {
+			return
+		}
+		meeting.RecordingFileID = &file.ID
+		meeting.RecordingPath = &file.Key
 	}
 
 	createdMeeting, err := h.MeetingService.CreateMeeting(&meeting)
@@ -68,29 +105,17 @@ func (h *MeetingHandler) CreateMeeting(c *gin.Context) {
 }
 
 func (h *MeetingHandler) GetMeeting(c *gin.Context) {
-	meetingId := c.Param("id")
-	id, err := strconv.ParseUint(meetingId, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+	meeting, ok := h.viewableMeeting(c)
+	if !ok {
 		return
 	}
-
-	meeting, err := h.MeetingService.GetMeeting(uint(id))
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Meeting not found"})
-			return
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-	}
 	c.JSON(http.StatusOK, meeting)
 }
 
+// GetAllMeetings lists the caller's meetings and the shared ones
 // TODO: Add pagination
 func (h *MeetingHandler) GetAllMeetings(c *gin.Context) {
-	meetings, err := h.MeetingService.GetAllMeetings()
+	meetings, err := h.MeetingService.GetAllMeetings(requestUserID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -99,12 +124,12 @@ func (h *MeetingHandler) GetAllMeetings(c *gin.Context) {
 }
 
 func (h *MeetingHandler) UpdateMeeting(c *gin.Context) {
-	meetingId := c.Param("id")
-	id, err := strconv.ParseUint(meetingId, 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+	// Only the owner may change a meeting
+	existing, ok := h.viewableMeeting(c)
+	if !ok {
 		return
 	}
+	id := existing.ID
 
 	var req UpdateMeetingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -124,8 +149,12 @@ func (h *MeetingHandler) UpdateMeeting(c *gin.Context) {
 	if req.MeetingURL != nil {
 		updates["meeting_url"] = *req.MeetingURL
 	}
-	if req.RecordingPath != nil {
-		updates["recording_path"] = *req.RecordingPath
+	if req.RecordingFileID != nil || req.RecordingPath != nil {
+		file, ok := h.resolveRecording(c, req.RecordingFileID, req.RecordingPath)
+		if !ok {
+			return
+		}
+		updates["recording_path"] = file.Key
 	}
 	if req.RecordingDurationSeconds != nil {
 		updates["recording_duration_seconds"] = *req.RecordingDurationSeconds
@@ -137,39 +166,407 @@ func (h *MeetingHandler) UpdateMeeting(c *gin.Context) {
 		updates["status"] = *req.Status
 	}
 
-	meeting, err := h.MeetingService.UpdateMeeting(uint(id), updates)
+	meeting, err := h.MeetingService.UpdateMeeting(id, updates, req.ReuseTranscript)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if meeting.RecordingPath != nil && meeting.Status == models.StatusCreated {
-		// Enqueue the meeting job
-		err = h.QueueService.EnqueueMeeting(meeting.ID, *meeting.RecordingPath)
+	c.JSON(http.StatusOK, meeting)
+}
+
+// AttachRecording is the completion callback for presigned uploads. It checks
+// the object really landed in storage and is audio before linking it to the
+// meeting.
+func (h *MeetingHandler) AttachRecording(c *gin.Context) {
+	// Only the owner may change a meeting
+	existing, ok := h.viewableMeeting(c)
+	if !ok {
+		return
+	}
+	id := existing.ID
+
+	var req AttachRecordingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, ok := h.resolveRecording(c, nil, &req.FileID)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	info, err := h.MeetingService.Store.Stat(ctx, file.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "File has not been uploaded"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The size on the file row was only declared by the client
+	if info.Size != file.SizeBytes {
+		if err := h.FileService.UpdateFileSize(file, info.Size); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Nothing has looked inside the object yet, so check it is really audio.
+	// Audio that was stored before is attached from the existing file.
+	if file.Format == nil {
+		analyzeCtx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+		defer cancel()
+		file, err = analyzeStoredUpload(analyzeCtx, h.MeetingService.Store, h.FileService, file)
 		if err != nil {
-			fmt.Printf("Failed to enqueue meeting job: %v", err)
+			respondAnalyzeError(c, err)
+			return
+		}
+	}
 
-		} else {
-			fmt.Println("Job enqueued successfully")
+	updates := map[string]interface{}{
+		"recording_path": file.Key,
+	}
+
+	meeting, err := h.MeetingService.UpdateMeeting(id, updates, req.ReuseTranscript)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Meeting not found"})
+			return
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
 	c.JSON(http.StatusOK, meeting)
 }
 
-func (h *MeetingHandler) DeleteMeeting(c *gin.Context) {
-	meetingId := c.Param("id")
-	id, err := strconv.ParseUint(meetingId, 10, 32)
+// StreamRecording proxies the meeting's recording so private buckets can be
+// played back in the browser. Range, If-Range and conditional requests are
+// handled by http.ServeContent; only the requested bytes are read from storage.
+func (h *MeetingHandler) StreamRecording(c *gin.Context) {
+	meeting, ok := h.viewableMeeting(c)
+	if !ok {
+		return
+	}
+	if meeting.RecordingExpiredAt != nil && meeting.RecordingPath == nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Recording was deleted by the retention policy"})
+		return
+	}
+	if meeting.RecordingPath == nil || *meeting.RecordingPath == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Meeting has no recording"})
+		return
+	}
+
+	// Archived recordings are read from the cold tier while they are restored
+	tier, err := h.Tiering.Touch(*meeting.RecordingPath)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		log.Printf("Failed to record playback of %s: %v", *meeting.RecordingPath, err)
+	}
+	if tier != "" {
+		c.Header("X-Storage-Tier", string(tier))
+	}
+
+	ctx := c.Request.Context()
+	info, err := h.MeetingService.Store.Stat(ctx, *meeting.RecordingPath)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Recording not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	contentType := info.ContentType
+	if contentType == "" && meeting.RecordingMimeType != nil {
+		contentType = *meeting.RecordingMimeType
+	}
+	if contentType != "" {
+		c.Header("Content-Type", contentType)
+	}
+	if info.ETag != "" {
+		c.Header("ETag", info.ETag)
+	}
+	c.Header("Cache-Control", "private, no-cache")
+
+	reader := storage.NewObjectReader(ctx, h.MeetingService.Store, info)
+	defer reader.Close()
+
+	http.ServeContent(c.Writer, c.Request, path.Base(info.Key), info.LastModified, reader)
+}
+
+// GetTranscript serves the meeting's transcript as plain text, loading it from
+// storage when it was too large to keep in the database
+func (h *MeetingHandler) GetTranscript(c *gin.Context) {
+	meeting, ok := h.viewableMeeting(c)
+	if !ok {
+		return
+	}
+
+	reader, err := h.Transcripts.Open(c.Request.Context(), meeting)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNoTranscript) && meeting.TranscriptExpiredAt != nil:
+			c.JSON(http.StatusGone, gin.H{"error": "Transcript was deleted by the retention policy"})
+		case errors.Is(err, services.ErrNoTranscript), errors.Is(err, storage.ErrObjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Meeting has no transcript"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	defer reader.Close()
+
+	size := int64(-1)
+	if meeting.Transcript != nil {
+		size = int64(len(*meeting.Transcript))
+	} else if meeting.TranscriptSizeBytes != nil {
+		size = *meeting.TranscriptSizeBytes
+	}
+	c.Header("Cache-Control", "private, no-cache")
+	c.DataFromReader(http.StatusOK, size, "text/plain; charset=utf-8", reader, nil)
+}
+
+// PutTranscript stores the transcript the worker produced; the route only
+// admits the worker token. The body is the raw text; large transcripts go to
+// storage instead of the meetings table.
+func (h *MeetingHandler) PutTranscript(c *gin.Context) {
+	meeting, ok := h.viewableMeeting(c)
+	if !ok {
 		return
 	}
 
-	err = h.MeetingService.DeleteMeeting(uint(id))
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxTranscriptSize))
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Transcript is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !utf8.Valid(body) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Transcript must be UTF-8 text"})
+		return
+	}
+
+	if err := h.Transcripts.Save(c.Request.Context(), meeting.ID, string(body)); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Meeting not found"})
 			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	meeting, err = h.MeetingService.GetMeeting(meeting.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"transcript_key":        meeting.TranscriptKey,
+		"transcript_size_bytes": meeting.TranscriptSizeBytes,
+	})
+}
+
+// GetMeetingJobs lists every attempt at processing the meeting, newest first
+func (h *MeetingHandler) GetMeetingJobs(c *gin.Context) {
+	meeting, ok := h.viewableMeeting(c)
+	if !ok {
+		return
+	}
+
+	jobs, err := h.Jobs.ListForMeeting(meeting.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// StreamMeetingEvents streams the meeting's processing progress and status
+// changes as Server-Sent Events. It starts with the current status, then
+// replays the events after Last-Event-ID (or the last_event_id query
+// parameter, for clients that can't set headers) before following live ones.
+func (h *MeetingHandler) StreamMeetingEvents(c *gin.Context) {
+	meeting, ok := h.viewableMeeting(c)
+	if !ok {
+		return
+	}
+
+	lastID := int64(0)
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+		lastID = id
+	}
+
+	// Subscribe before reading the backlog, so nothing published in between is lost
+	ctx := c.Request.Context()
+	pubsub, err := h.Events.Subscribe(ctx, meeting.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer pubsub.Close()
+	backlog, err := h.Events.Since(ctx, meeting.ID, lastID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// The snapshot has no ID, so it doesn't move the client's resume point
+	snapshot, err := json.Marshal(services.MeetingEvent{
+		Type:    services.EventStatus,
+		Status:  meeting.Status,
+		Message: stringValue(meeting.FailureReason),
+		At:      meeting.UpdatedAt,
+	})
+	if err != nil {
+		return
+	}
+	writeEvent(c, 0, services.EventStatus, string(snapshot))
+	for _, payload := range backlog {
+		lastID = h.writePublished(c, payload, lastID)
+	}
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			lastID = h.writePublished(c, message.Payload, lastID)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writePublished sends an event unless the client already has it, and
+// returns the ID the client has seen up to
+func (h *MeetingHandler) writePublished(c *gin.Context, payload string, lastID int64) int64 {
+	var event services.MeetingEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		log.Printf("Skipping unreadable meeting event: %v", err)
+		return lastID
+	}
+	if event.ID <= lastID {
+		return lastID
+	}
+	writeEvent(c, event.ID, event.Type, payload)
+	return event.ID
+}
+
+func writeEvent(c *gin.Context, id int64, name, data string) {
+	if id > 0 {
+		fmt.Fprintf(c.Writer, "id: %d\n", id)
+	}
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", name, data)
+	c.Writer.Flush()
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// viewableMeeting loads the meeting named in the URL and checks the caller may
+// see it, writing the error response if not
+func (h *MeetingHandler) viewableMeeting(c *gin.Context) (*models.Meeting, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return nil, false
+	}
+
+	meeting, err := h.MeetingService.GetMeeting(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Meeting not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if !canView(meeting, requestUserID(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Meeting belongs to another user"})
+		return nil, false
+	}
+	return meeting, true
+}
+
+// canView reports whether a user may see a meeting. Meetings without an owner
+// are shared until authentication lands.
+func canView(meeting *models.Meeting, userID *uint) bool {
+	return meeting.UserID == nil || (userID != nil && *userID == *meeting.UserID)
+}
+
+// resolveRecording looks up the file a request wants to attach and writes the
+// error response if it can't be used
+func (h *MeetingHandler) resolveRecording(c *gin.Context, fileID *uint, key *string) (*models.File, bool) {
+	file, err := h.FileService.ResolveRecording(fileID, key, requestUserID(c))
+	switch {
+	case err == nil:
+		return file, true
+	case errors.Is(err, services.ErrUnknownFile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Recording was not uploaded"})
+	case errors.Is(err, services.ErrFileNotOwned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Recording belongs to another user"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+	return nil, false
+}
+
+func (h *MeetingHandler) DeleteMeeting(c *gin.Context) {
+	// Only the owner may change a meeting
+	existing, ok := h.viewableMeeting(c)
+	if !ok {
+		return
+	}
+	id := existing.ID
+
+	err := h.MeetingService.DeleteMeeting(id)
+	if err != nil {
+		if errors.Is(err, services.ErrDeletionPending) {
+			// The meeting is already hidden, storage cleanup will be retried in the background
+			c.JSON(http.StatusAccepted, gin.H{"message": "Meeting deletion pending"})
+			return
+		} else if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Meeting not found"})
+			return
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/services"
+)
+
+type QueueHandler struct {
+	Queue   services.Queue
+	Backend string
+}
+
+func NewQueueHandler(queue services.Queue, backend string) *QueueHandler {
+	return &QueueHandler{Queue: queue, Backend: backend}
+}
+
+// GetQueue reports which backend holds the jobs and how many are waiting
+func (h *QueueHandler) GetQueue(c *gin.Context) {
+	depth, err := h.Queue.Depth(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"backend": h.Backend, "depth": depth})
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/services"
+)
+
+func newQueueRouter(queue services.Queue, adminToken string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/queue", RequireToken(AdminTokenHeader, adminToken), NewQueueHandler(queue, "memory").GetQueue)
+	return router
+}
+
+func TestGetQueue(t *testing.T) {
+	queue := services.NewMemoryQueue(3)
+	for id := uint(1); id <= 2; id++ {
+		queue.Enqueue(context.Background(), services.NewMeetingJob(id, "a.mp3", nil))
+	}
+	router := newQueueRouter(queue, "secret")
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/queue", nil)
+	req.Header.Set(AdminTokenHeader, "secret")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Backend string `json:"backend"`
+		Depth   int64  `json:"depth"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Backend != "memory" || body.Depth != 2 {
+		t.Fatalf("got %+v, want the memory backend with 2 jobs", body)
+	}
+}
+
+func TestGetQueueRequiresAdminToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		sent       string
+		want       int
+	}{
+		{"missing token", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "guess", http.StatusUnauthorized},
+		{"not configured", "", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newQueueRouter(services.NewMemoryQueue(3), tt.configured)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/queue", nil)
+			if tt.sent != "" {
+				req.Header.Set(AdminTokenHeader, tt.sent)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userHeader identifies the caller until authentication is in place.
+//
+// Nothing checks it: any client can leave it out or name any user. Ownership
+// of meetings and files, quotas and storage profiles are keyed on it, so they
+// only keep honest clients apart and are not a security boundary. Without the
+// header a request is anonymous, and what it uploads is visible to everyone
+// and counted against the shared pool. Settings that would let a spoofed
+// header hurt another user, such as storage profiles, retention policies and
+// quotas, are behind the admin token instead. Don't build further per-user
+// protection on this header until a middleware authenticates it, e.g. by
+// checking a signed token.
+const userHeader = "X-User-ID"
+
+// requestUserID returns the user the request claims to come from, or nil for
+// anonymous requests. See userHeader for how far that can be trusted.
+func requestUserID(c *gin.Context) *uint {
+	id, err := strconv.ParseUint(c.GetHeader(userHeader), 10, 32)
+	if err != nil {
+		return nil
+	}
+	userID := uint(id)
+	return &userID
+}
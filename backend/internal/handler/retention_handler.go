@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"github.com/jaykapade/meeting-assistant/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+// RetentionRequest sets retention in days; omitted or null inherits, 0 keeps forever
+type RetentionRequest struct {
+	RecordingDays  *int `json:"recording_days" binding:"omitempty,min=0"`
+	TranscriptDays *int `json:"transcript_days" binding:"omitempty,min=0"`
+}
+
+type LegalHoldRequest struct {
+	LegalHold *bool `json:"legal_hold" binding:"required"`
+}
+
+type RetentionHandler struct {
+	Retention      *services.RetentionService
+	MeetingService *services.MeetingService
+}
+
+func NewRetentionHandler(retention *services.RetentionService, ms *services.MeetingService) *RetentionHandler {
+	return &RetentionHandler{Retention: retention, MeetingService: ms}
+}
+
+// GetPolicy shows the global defaults and the caller's workspace overrides
+func (h *RetentionHandler) GetPolicy(c *gin.Context) {
+	response := gin.H{
+		"global": gin.H{
+			"recording_days":  h.Retention.Defaults.RecordingDays,
+			"transcript_days": h.Retention.Defaults.TranscriptDays,
+		},
+		"workspace": nil,
+	}
+
+	if userID := requestUserID(c); userID != nil {
+		policy, err := h.Retention.GetPolicy(*userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if policy != nil {
+			response["workspace"] = policy
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SetPolicy replaces the workspace overrides of the user in X-User-ID; the
+// route only admits the admin token
+func (h *RetentionHandler) SetPolicy(c *gin.Context) {
+	userID := requestUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Retention settings need a user"})
+		return
+	}
+
+	var req RetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.Retention.SetPolicy(*userID, req.RecordingDays, req.TranscriptDays)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// GetMeetingRetention explains when the meeting's artifacts expire and what
+// has been removed already
+func (h *RetentionHandler) GetMeetingRetention(c *gin.Context) {
+	meeting, ok := h.loadMeeting(c)
+	if !ok {
+		return
+	}
+	h.respondMeetingRetention(c, meeting)
+}
+
+// SetMeetingRetention replaces the meeting's overrides
+func (h *RetentionHandler) SetMeetingRetention(c *gin.Context) {
+	var req RetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meeting, ok := h.loadMeeting(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Retention.SetMeetingRetention(meeting, req.RecordingDays, req.TranscriptDays); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	meeting.RecordingRetentionDays = req.RecordingDays
+	meeting.TranscriptRetentionDays = req.TranscriptDays
+	h.respondMeetingRetention(c, meeting)
+}
+
+// SetLegalHold places or lifts a legal hold, which blocks all expiry. The
+// route only admits the admin token.
+func (h *RetentionHandler) SetLegalHold(c *gin.Context) {
+	var req LegalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meeting, ok := h.loadMeeting(c)
+	if !ok {
+		return
+	}
+
+	if err := h.Retention.SetLegalHold(meeting, *req.LegalHold); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	meeting.LegalHold = *req.LegalHold
+	h.respondMeetingRetention(c, meeting)
+}
+
+func (h *RetentionHandler) loadMeeting(c *gin.Context) (*models.Meeting, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return nil, false
+	}
+
+	meeting, err := h.MeetingService.GetMeeting(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Meeting not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if !canView(meeting, requestUserID(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Meeting belongs to another user"})
+		return nil, false
+	}
+	return meeting, true
+}
+
+func (h *RetentionHandler) respondMeetingRetention(c *gin.Context, meeting *models.Meeting) {
+	recording, transcript, err := h.Retention.MeetingRules(meeting)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	audits, err := h.Retention.GetAudits(meeting.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"meeting_id": meeting.ID,
+		"legal_hold": meeting.LegalHold,
+		"recording":  artifactRetention(meeting, recording, meeting.RecordingExpiredAt),
+		"transcript": artifactRetention(meeting, transcript, meeting.TranscriptExpiredAt),
+		"audit":      audits,
+	})
+}
+
+func artifactRetention(meeting *models.Meeting, rule services.RetentionRule, expiredAt *time.Time) gin.H {
+	response := gin.H{
+		"days":       rule.Days,
+		"source":     rule.Source,
+		"expires_at": nil,
+		"expired_at": expiredAt,
+	}
+	if rule.Days > 0 && expiredAt == nil {
+		response["expires_at"] = meeting.CreatedAt.AddDate(0, 0, rule.Days)
+	}
+	return response
+}
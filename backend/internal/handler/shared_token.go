@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// AdminTokenHeader carries the operator secret for the /admin endpoints
+	AdminTokenHeader = "X-Admin-Token"
+	// WorkerTokenHeader carries the secret shared with the AI worker for the
+	// endpoints it writes results through
+	WorkerTokenHeader = "X-Worker-Token"
+)
+
+// RequireToken only lets through requests that carry secret in header. With
+// no secret configured the routes stay closed rather than becoming public.
+func RequireToken(header, secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if secret == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Endpoint is disabled until its token is configured"})
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader(header)), []byte(secret)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid " + header})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/services"
+	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
+)
+
+type UpdateStorageCredentialsRequest struct {
+	AccessKey string `json:"access_key" binding:"required"`
+	SecretKey string `json:"secret_key" binding:"required"`
+}
+
+type StorageHandler struct {
+	Profiles *services.StorageProfileService
+	Router   *storage.Router
+	// Only set when storage replicas are configured
+	Mirror *storage.MirroredProvider
+}
+
+func NewStorageHandler(profiles *services.StorageProfileService, router *storage.Router, mirror *storage.MirroredProvider) *StorageHandler {
+	return &StorageHandler{Profiles: profiles, Router: router, Mirror: mirror}
+}
+
+// ReplicationStatus reports lag and failures for each storage replica
+func (h *StorageHandler) ReplicationStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"replicas": h.Mirror.Status()})
+}
+
+// GetProfile returns where the caller's new uploads are stored
+func (h *StorageHandler) GetProfile(c *gin.Context) {
+	profile, err := h.Profiles.ActiveProfile(requestUserID(c))
+	if err != nil {
+		h.respondProfileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// CreateProfile moves the caller's new uploads to their own bucket or prefix.
+// Recordings uploaded before stay where they are.
+func (h *StorageHandler) CreateProfile(c *gin.Context) {
+	userID := requestUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Storage settings need a user"})
+		return
+	}
+
+	var req services.StorageProfileInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.Profiles.CreateProfile(c.Request.Context(), *userID, req)
+	if err != nil {
+		h.respondProfileError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, profile)
+}
+
+// UpdateCredentials rotates the keys used for the caller's bucket
+func (h *StorageHandler) UpdateCredentials(c *gin.Context) {
+	userID := requestUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Storage settings need a user"})
+		return
+	}
+
+	var req UpdateStorageCredentialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.Profiles.UpdateCredentials(c.Request.Context(), *userID, req.AccessKey, req.SecretKey)
+	if err != nil {
+		h.respondProfileError(c, err)
+		return
+	}
+	h.Router.Forget(profile.ID)
+	c.JSON(http.StatusOK, profile)
+}
+
+// RetireProfile sends the caller's new uploads back to the default store
+func (h *StorageHandler) RetireProfile(c *gin.Context) {
+	userID := requestUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Storage settings need a user"})
+		return
+	}
+
+	if err := h.Profiles.RetireProfile(*userID); err != nil {
+		h.respondProfileError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *StorageHandler) respondProfileError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNoStorageProfile):
+		c.JSON(http.StatusNotFound, gin.H{"error": "No storage profile, uploads use the default bucket"})
+	case errors.Is(err, services.ErrInvalidStorageProfile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrBucketUnreachable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCredentialsUnavailable):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Bucket credentials can't be stored until STORAGE_CREDENTIALS_KEYS is set"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jaykapade/meeting-assistant/backend/internal/audio"
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"github.com/jaykapade/meeting-assistant/backend/internal/services"
+	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
+)
+
+const tusVersion = "1.0.0"
+
+// TusHandler implements the core tus 1.0 protocol plus the creation and
+// termination extensions, so long recordings can be resumed after a drop.
+type TusHandler struct {
+	Sessions *services.UploadSessionService
+	Files    *services.FileService
+	Profiles *services.StorageProfileService
+	Quotas   *services.QuotaService
+	MaxSize  int64
+}
+
+func NewTusHandler(sessions *services.UploadSessionService, files *services.FileService, profiles *services.StorageProfileService, quotas *services.QuotaService, maxSize int64) *TusHandler {
+	return &TusHandler{Sessions: sessions, Files: files, Profiles: profiles, Quotas: quotas, MaxSize: maxSize}
+}
+
+// Options advertises what this server supports
+func (h *TusHandler) Options(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", "creation,termination")
+	c.Header("Tus-Max-Size", strconv.FormatInt(h.MaxSize, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// CreateUpload reserves a storage key and returns the upload URL in Location
+func (h *TusHandler) CreateUpload(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	if !h.checkVersion(c) {
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Length header is required"})
+		return
+	}
+	if length > h.MaxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": sizeLimitError(h.MaxSize)})
+		return
+	}
+
+	metadata := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	filename := metadata["filename"]
+	ext, ok := audioExtension(filename)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only .mp3, .wav, and .m4a files are allowed"})
+		return
+	}
+
+	userID := requestUserID(c)
+	profileID, err := h.Profiles.UploadProfileID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load storage settings"})
+		return
+	}
+
+	// Upload-Length is binding, so the whole upload is counted up front
+	if err := h.Quotas.Check(userID, profileID, length); err != nil {
+		respondQuotaError(c, err)
+		return
+	}
+
+	key := storage.ProfileKey(profileID, uuid.New().String()+ext)
+	session, err := h.Sessions.Create(c.Request.Context(), key, filename, metadata["filetype"], length)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload", "details": err.Error()})
+		return
+	}
+
+	// Record the key now so a meeting can only attach keys we handed out
+	_, err = h.Files.CreateFile(&models.File{
+		Key:              key,
+		OriginalFilename: filename,
+		SizeBytes:        length,
+		MimeType:         metadata["filetype"],
+		UploaderID:       userID,
+		StorageProfileID: profileID,
+	})
+	if err != nil {
+		h.Sessions.Abort(context.Background(), session.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record upload", "details": err.Error()})
+		return
+	}
+
+	// The key is known up front; it becomes usable once the upload completes
+	c.Header("Location", c.Request.URL.Path+"/"+session.ID)
+	c.Header("Upload-File-Id", session.Key)
+	c.Status(http.StatusCreated)
+}
+
+// GetOffset tells the client where to resume from
+func (h *TusHandler) GetOffset(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Cache-Control", "no-store")
+
+	session, err := h.Sessions.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Length, 10))
+	c.Header("Upload-File-Id", session.Key)
+	c.Status(http.StatusOK)
+}
+
+// PatchUpload appends the request body at Upload-Offset
+func (h *TusHandler) PatchUpload(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	if !h.checkVersion(c) {
+		return
+	}
+
+	if c.GetHeader("Content-Type") != "application/offset+octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/offset+octet-stream"})
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Offset header is required"})
+		return
+	}
+
+	// The first chunk carries the magic bytes, so reject non-audio before storing anything
+	var body io.Reader = c.Request.Body
+	if offset == 0 {
+		stream := bufio.NewReader(c.Request.Body)
+		head, _ := stream.Peek(audio.SniffSize)
+		if _, err := audio.Detect(head); err != nil {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "File content is not MP3, WAV or M4A audio"})
+			return
+		}
+		body = stream
+	}
+
+	session, err := h.Sessions.WriteChunk(c.Request.Context(), c.Param("id"), offset, body)
+	if session != nil {
+		c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+		c.Header("Upload-File-Id", session.Key)
+	}
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+
+	// Only the first chunk was sniffed, so check the whole recording once it is in
+	if session.Completed {
+		file, err := h.Files.GetFileByKey(session.Key)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load upload", "details": err.Error()})
+			return
+		}
+		file, err = analyzeStoredUpload(c.Request.Context(), h.Sessions.Store, h.Files, file)
+		if err != nil {
+			respondAnalyzeError(c, err)
+			return
+		}
+		// Identical audio was uploaded before, point the client at that file
+		c.Header("Upload-File-Id", file.Key)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteUpload aborts the upload and discards stored parts
+func (h *TusHandler) DeleteUpload(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	if !h.checkVersion(c) {
+		return
+	}
+
+	if err := h.Sessions.Abort(c.Request.Context(), c.Param("id")); err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *TusHandler) checkVersion(c *gin.Context) bool {
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Unsupported tus version"})
+		return false
+	}
+	return true
+}
+
+func (h *TusHandler) writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUploadSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+	case errors.Is(err, services.ErrUploadOffsetMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload-Offset does not match the current offset"})
+	case errors.Is(err, services.ErrUploadSessionLocked):
+		c.JSON(http.StatusLocked, gin.H{"error": "Upload is already in progress"})
+	default:
+		log.Printf("Resumable upload failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write upload", "details": err.Error()})
+	}
+}
+
+// parseUploadMetadata decodes "key base64value,key base64value"
+func parseUploadMetadata(header string) map[string]string {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 {
+			continue
+		}
+		var value string
+		if len(fields) > 1 {
+			decoded, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				continue
+			}
+			value = string(decoded)
+		}
+		metadata[fields[0]] = value
+	}
+	return metadata
+}
@@ -1,94 +1,571 @@
 package handler
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jaykapade/meeting-assistant/backend/internal/audio"
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"github.com/jaykapade/meeting-assistant/backend/internal/services"
 	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
 )
 
+type PresignUploadRequest struct {
+	Filename    string `json:"filename" binding:"required"`
+	ContentType string `json:"content_type" binding:"required"`
+	Size        int64  `json:"size" binding:"required,gt=0"`
+}
+
 type UploadHandler struct {
-	Store   storage.Provider
-	MaxSize int64
+	Store        storage.Provider
+	Files        *services.FileService
+	Profiles     *services.StorageProfileService
+	Tiering      *services.TieringService
+	Quotas       *services.QuotaService
+	MaxSize      int64
+	SignedURLTTL time.Duration
 }
 
-func NewUploadHandler(store storage.Provider) *UploadHandler {
+func NewUploadHandler(store storage.Provider, files *services.FileService, profiles *services.StorageProfileService, tiering *services.TieringService, quotas *services.QuotaService, maxSize int64, signedURLTTL time.Duration) *UploadHandler {
 	return &UploadHandler{
-		Store:   store,
-		MaxSize: 50 << 20, // 50 MB
+		Store:        store,
+		Files:        files,
+		Profiles:     profiles,
+		Tiering:      tiering,
+		Quotas:       quotas,
+		MaxSize:      maxSize,
+		SignedURLTTL: signedURLTTL,
+	}
+}
+
+// audioExtension returns the lowercased extension if it is an accepted audio format
+func audioExtension(filename string) (string, bool) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return ext, ext == ".mp3" || ext == ".wav" || ext == ".m4a"
+}
+
+func sizeLimitError(maxSize int64) string {
+	return fmt.Sprintf("File size exceeds %dMB", maxSize>>20)
+}
+
+// Uploads land here until their content hash is known
+const incomingPrefix = "incoming/"
+
+// Room for multipart boundaries and part headers on top of the file itself
+const multipartOverhead = 64 << 10
+
+var errFileTooLarge = errors.New("file exceeds the upload size limit")
+
+// sizeLimitReader fails the read as soon as more than max bytes have arrived
+type sizeLimitReader struct {
+	r   io.Reader
+	n   int64
+	max int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.n > l.max {
+		return n, errFileTooLarge
 	}
+	return n, err
 }
 
 func (h *UploadHandler) UploadFile(c *gin.Context) {
-	// 1. Get the file from the request
-	fileHeader, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+	// 1. Reject early when the client already tells us the body is too big
+	if c.Request.ContentLength > h.MaxSize+multipartOverhead {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": sizeLimitError(h.MaxSize)})
 		return
 	}
 
-	// 2. Validate the file size
-	if fileHeader.Size > h.MaxSize {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File size exceeds 50MB"})
+	// 2. Find the file part without buffering the body to memory or disk
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expected a multipart/form-data body"})
 		return
 	}
 
+	var part *multipart.Part
+	for {
+		part, err = reader.NextPart()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+			return
+		}
+		if part.FormName() == "file" && part.FileName() != "" {
+			break
+		}
+		part.Close()
+	}
+	defer part.Close()
+
 	// 3. Validate the file extension
-	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
-	if ext != ".mp3" && ext != ".wav" && ext != ".m4a" {
+	filename := part.FileName()
+	ext, ok := audioExtension(filename)
+	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Only .mp3, .wav, and .m4a files are allowed"})
 		return
 	}
 
-	fileStream, err := fileHeader.Open()
+	// 4. Sniff the real format from the magic bytes instead of trusting the client
+	stream := bufio.NewReader(part)
+	head, _ := stream.Peek(audio.SniffSize)
+	format, err := audio.Detect(head)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "File content is not MP3, WAV or M4A audio"})
+		return
+	}
+
+	// 5. Stream to a temporary key in the user's bucket; the final key depends on the content hash
+	userID := requestUserID(c)
+	profileID, err := h.Profiles.UploadProfileID(userID)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to open file stream"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load storage settings"})
 		return
 	}
-	defer fileStream.Close()
+	incomingKey := storage.ProfileKey(profileID, incomingPrefix+uuid.New().String()+ext)
 
-	// 4. Generate a unique filename
-	key := uuid.New().String() + ext
+	// Refuse what the owner's quota can't take before reading any data, and
+	// stop the stream once it would go over
+	maxSize := h.MaxSize
+	remaining, limited, err := h.Quotas.Remaining(userID, profileID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check storage quota"})
+		return
+	}
+	if limited {
+		if c.Request.ContentLength > remaining+multipartOverhead {
+			respondQuotaError(c, h.Quotas.Exceeded(userID, c.Request.ContentLength))
+			return
+		}
+		maxSize = min(maxSize, remaining)
+	}
 
-	// 5. Upload the file to the storage provider with timeout
-	// Use 5 minutes to allow for large file uploads (up to 50MB)
+	// 6. Stream the part to the storage provider with timeout
+	// Use 5 minutes to allow for large file uploads
 	uploadCtx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
 	defer cancel()
 
-	result, err := h.Store.Upload(uploadCtx, fileStream, key, fileHeader.Header.Get("Content-Type"))
+	// The limit is enforced on the bytes actually received, not a client-supplied size,
+	// and the hash and audio headers are computed as the bytes stream past
+	hash := sha256.New()
+	analyzer := audio.NewAnalyzer()
+	body := &sizeLimitReader{r: analyzer.Reader(io.TeeReader(stream, hash)), max: maxSize}
+	result, err := h.Store.Upload(uploadCtx, body, incomingKey, format.MimeType())
+	if err != nil {
+		switch {
+		case errors.Is(err, errFileTooLarge) && maxSize < h.MaxSize:
+			respondQuotaError(c, h.Quotas.Exceeded(userID, body.n))
+		case errors.Is(err, errFileTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": sizeLimitError(h.MaxSize)})
+		case errors.Is(err, audio.ErrUnsupportedFormat), errors.Is(err, audio.ErrInvalidAudio):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Audio file is corrupt or unreadable"})
+		default:
+			c.JSON(500, gin.H{"error": "Failed to upload to storage", "details": err.Error()})
+		}
+		return
+	}
+	// The temporary object is never needed past this request
+	defer h.Store.Delete(context.Background(), incomingKey)
+
+	// 7. Reject files whose headers don't parse
+	metadata, err := analyzer.Result()
 	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Audio file is corrupt or unreadable"})
+		return
+	}
+
+	// 8. Identical audio was uploaded before, hand back the existing file
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if existing, err := h.Files.GetFileByChecksum(profileID, checksum); err == nil {
+		h.respondDuplicate(c, existing, filename)
+		return
+	}
+
+	key := storage.ProfileKey(profileID, checksum+"."+string(format))
+	if err := h.Store.Copy(uploadCtx, incomingKey, key); err != nil {
 		c.JSON(500, gin.H{"error": "Failed to upload to storage", "details": err.Error()})
 		return
 	}
 
-	// 6. Return the success response
-	c.JSON(200, gin.H{"message": "File uploaded successfully", "file_id": result.Key, "filename": fileHeader.Filename})
+	// 9. Record the file so the meeting picks up the measured details
+	file, err := h.Files.CreateFile(&models.File{
+		Key:              key,
+		OriginalFilename: filename,
+		SizeBytes:        result.Size,
+		MimeType:         metadata.MimeType,
+		Checksum:         &checksum,
+		UploaderID:       userID,
+		StorageProfileID: profileID,
+		Format:           (*string)(&metadata.Format),
+		DurationSeconds:  &metadata.DurationSeconds,
+		SampleRate:       &metadata.SampleRate,
+		Channels:         &metadata.Channels,
+		Bitrate:          &metadata.Bitrate,
+	})
+	if err != nil {
+		// A concurrent upload of the same audio won the unique checksum
+		if existing, lookupErr := h.Files.GetFileByChecksum(profileID, checksum); lookupErr == nil {
+			h.respondDuplicate(c, existing, filename)
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to record upload", "details": err.Error()})
+		return
+	}
+
+	// 10. Return the success response
+	c.JSON(200, gin.H{
+		"message":   "File uploaded successfully",
+		"id":        file.ID,
+		"file_id":   file.Key,
+		"filename":  filename,
+		"size":      file.SizeBytes,
+		"metadata":  metadata,
+		"duplicate": false,
+	})
+
+}
+
+func (h *UploadHandler) GetAllFiles(c *gin.Context) {
+	files, err := h.Files.GetAllFiles(requestUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, files)
+}
+
+// GetFile describes an uploaded file and the meetings using it
+func (h *UploadHandler) GetFile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	fileID := uint(id)
+	file, ok := h.resolveFile(c, &fileID, nil)
+	if !ok {
+		return
+	}
+
+	meetingIDs, err := h.Files.GetFileMeetingIDs(file.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"file":        file,
+		"meeting_ids": meetingIDs,
+	})
+}
+
+// resolveFile looks up an uploaded file by ID or key and checks the caller
+// may use it, writing the error response if not
+func (h *UploadHandler) resolveFile(c *gin.Context, fileID *uint, key *string) (*models.File, bool) {
+	file, err := h.Files.ResolveRecording(fileID, key, requestUserID(c))
+	switch {
+	case err == nil:
+		return file, true
+	case errors.Is(err, services.ErrUnknownFile):
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+	case errors.Is(err, services.ErrFileNotOwned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "File belongs to another user"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+	return nil, false
+}
+
+// respondQuotaError writes the 413 for an upload that doesn't fit in the
+// owner's quota, or a 500 if the quota couldn't be checked
+func respondQuotaError(c *gin.Context, err error) {
+	var quotaErr *services.QuotaError
+	if !errors.As(err, &quotaErr) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check storage quota"})
+		return
+	}
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":           "Storage quota exceeded, delete recordings or ask for a larger quota",
+		"used_bytes":      quotaErr.UsedBytes,
+		"quota_bytes":     quotaErr.QuotaBytes,
+		"requested_bytes": quotaErr.RequestedBytes,
+	})
+}
+
+// respondDuplicate returns an already stored file, pointing at a finished
+// meeting so the client can offer to reuse its transcript
+func (h *UploadHandler) respondDuplicate(c *gin.Context, file *models.File, filename string) {
+	// The caller proved they have the audio, so they may attach the file
+	if err := h.Files.GrantFile(file, requestUserID(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record upload", "details": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"message":   "File already uploaded",
+		"id":        file.ID,
+		"file_id":   file.Key,
+		"filename":  filename,
+		"size":      file.SizeBytes,
+		"metadata":  fileMetadata(file),
+		"duplicate": true,
+	}
+	if meeting, err := h.Files.GetProcessedMeeting(file.Key, requestUserID(c)); err == nil {
+		response["existing_meeting_id"] = meeting.ID
+	}
+	c.JSON(200, response)
+}
+
+// analyzeStoredUpload reads back an upload that went straight to storage and
+// records its audio details and checksum. Content that isn't audio is deleted.
+// If identical audio was stored before, the upload is dropped in favour of
+// that file, which is returned instead.
+func analyzeStoredUpload(ctx context.Context, store storage.Provider, files *services.FileService, file *models.File) (*models.File, error) {
+	reader, err := store.Open(ctx, file.Key, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	metadata, err := audio.Analyze(io.TeeReader(reader, hash))
+	reader.Close()
+
+	if errors.Is(err, audio.ErrUnsupportedFormat) || errors.Is(err, audio.ErrInvalidAudio) {
+		discardUpload(ctx, store, files, file)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if existing, err := files.GetFileByChecksum(file.StorageProfileID, checksum); err == nil && existing.ID != file.ID {
+		return keepExistingUpload(ctx, store, files, file, existing)
+	}
+	if err := files.RecordAudioMetadata(file, metadata, checksum); err != nil {
+		// A concurrent upload of the same audio won the unique checksum
+		if existing, lookupErr := files.GetFileByChecksum(file.StorageProfileID, checksum); lookupErr == nil && existing.ID != file.ID {
+			return keepExistingUpload(ctx, store, files, file, existing)
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// keepExistingUpload drops a duplicate upload and lets its uploader attach the
+// file that already holds the same audio
+func keepExistingUpload(ctx context.Context, store storage.Provider, files *services.FileService, file, existing *models.File) (*models.File, error) {
+	if err := files.GrantFile(existing, file.UploaderID); err != nil {
+		return nil, err
+	}
+	discardUpload(ctx, store, files, file)
+	return existing, nil
+}
+
+// discardUpload deletes an upload's row and object, unless a meeting uses it
+func discardUpload(ctx context.Context, store storage.Provider, files *services.FileService, file *models.File) {
+	discarded, err := files.DiscardFile(file)
+	if err != nil {
+		log.Printf("Failed to discard upload %s: %v", file.Key, err)
+	}
+	if discarded {
+		if err := store.Delete(context.WithoutCancel(ctx), file.Key); err != nil {
+			log.Printf("Failed to delete upload %s: %v", file.Key, err)
+		}
+	}
+}
+
+// respondAnalyzeError reports why a stored upload couldn't be analyzed
+func respondAnalyzeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, audio.ErrUnsupportedFormat):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "File content is not MP3, WAV or M4A audio"})
+	case errors.Is(err, audio.ErrInvalidAudio):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Audio file is corrupt or unreadable"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read upload", "details": err.Error()})
+	}
+}
 
+// fileMetadata rebuilds the upload-time audio metadata from a stored file
+func fileMetadata(file *models.File) *audio.Metadata {
+	metadata := &audio.Metadata{MimeType: file.MimeType}
+	if file.Format != nil {
+		metadata.Format = audio.Format(*file.Format)
+	}
+	if file.DurationSeconds != nil {
+		metadata.DurationSeconds = *file.DurationSeconds
+	}
+	if file.SampleRate != nil {
+		metadata.SampleRate = *file.SampleRate
+	}
+	if file.Channels != nil {
+		metadata.Channels = *file.Channels
+	}
+	if file.Bitrate != nil {
+		metadata.Bitrate = *file.Bitrate
+	}
+	return metadata
 }
 
 func (h *UploadHandler) DownloadFile(c *gin.Context) {
-	fileId := c.Param("file_id") // This is the 'Key' (e.g. uuid.mp3)
+	fileId := strings.TrimPrefix(c.Param("file_id"), "/") // This is the 'Key' (e.g. uuid.mp3 or profiles/12/<sha>.mp3)
+
+	// Only keys we recorded are signed, and only for users who may see them
+	if _, ok := h.resolveFile(c, nil, &fileId); !ok {
+		return
+	}
 
 	// 1. Get the signed URL
 	// We use a short timeout (5s) because generating a string is fast
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	url, err := h.Store.GetSignedURL(ctx, fileId)
+	// Optional overrides so the browser saves e.g. "standup.mp3" instead of the uuid
+	contentType, ok := downloadContentType(c.Query("content_type"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content_type must be an audio type"})
+		return
+	}
+	opts := storage.SignedURLOptions{
+		Expires:     h.SignedURLTTL,
+		Filename:    c.Query("filename"),
+		ContentType: contentType,
+	}
+	expiresAt := time.Now().Add(h.SignedURLTTL)
+
+	url, err := h.Store.GetSignedURL(ctx, fileId, opts)
+	if errors.Is(err, storage.ErrDirectAccessUnsupported) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Download links are disabled while storage is encrypted, stream the meeting recording instead"})
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to generate download link"})
 		return
 	}
 
+	// Archived files are served from the cold tier while they are restored.
+	// Only a download that got a link counts as a read.
+	tier, err := h.Tiering.Touch(fileId)
+	if err != nil {
+		log.Printf("Failed to record download of %s: %v", fileId, err)
+		tier = models.TierHot
+	}
+
 	// 2. Return it
 	c.JSON(200, gin.H{
 		"file_id":      fileId,
+		"tier":         tier,
 		"download_url": url,
-		"expires_in":   "15m",
+		"expires_in":   int(h.SignedURLTTL.Seconds()),
+		"expires_at":   expiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// downloadContentType checks a requested Content-Type override. Only audio
+// types are allowed, so a stored object can never be served as e.g. text/html
+// from the bucket's origin.
+func downloadContentType(value string) (string, bool) {
+	if value == "" {
+		return "", true
+	}
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil || !strings.HasPrefix(mediaType, "audio/") {
+		return "", false
+	}
+	return mediaType, true
+}
+
+// PresignUpload lets the client send the recording straight to storage.
+// Once the PUT succeeds the client attaches it with POST /meetings/:id/recording.
+func (h *UploadHandler) PresignUpload(c *gin.Context) {
+	var req PresignUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Size > h.MaxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": sizeLimitError(h.MaxSize)})
+		return
+	}
+
+	ext, ok := audioExtension(req.Filename)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only .mp3, .wav, and .m4a files are allowed"})
+		return
+	}
+	if !strings.HasPrefix(req.ContentType, "audio/") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content type must be an audio type"})
+		return
+	}
+
+	// The key is generated here so clients can't overwrite other objects
+	userID := requestUserID(c)
+	profileID, err := h.Profiles.UploadProfileID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load storage settings"})
+		return
+	}
+	if err := h.Quotas.Check(userID, profileID, req.Size); err != nil {
+		respondQuotaError(c, err)
+		return
+	}
+	key := storage.ProfileKey(profileID, uuid.New().String()+ext)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	expiresAt := time.Now().Add(h.SignedURLTTL)
+	upload, err := h.Store.GetSignedUploadURL(ctx, key, req.ContentType, req.Size, h.SignedURLTTL)
+	if errors.Is(err, storage.ErrDirectAccessUnsupported) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Direct uploads are disabled while storage is encrypted, use /file/uploads instead"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to generate upload link"})
+		return
+	}
+
+	// Record the key now so a meeting can only attach keys we handed out
+	file, err := h.Files.CreateFile(&models.File{
+		Key:              key,
+		OriginalFilename: req.Filename,
+		SizeBytes:        req.Size,
+		MimeType:         req.ContentType,
+		UploaderID:       userID,
+		StorageProfileID: profileID,
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to record upload", "details": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"id":         file.ID,
+		"file_id":    key,
+		"filename":   req.Filename,
+		"upload_url": upload.URL,
+		"method":     upload.Method,
+		"headers":    upload.Headers,
+		"expires_in": int(h.SignedURLTTL.Seconds()),
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
 	})
 }
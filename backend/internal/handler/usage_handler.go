@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/services"
+)
+
+type SetQuotaRequest struct {
+	// Omitted or null restores the default, 0 removes the limit
+	QuotaBytes *int64 `json:"quota_bytes" binding:"omitempty,min=0"`
+}
+
+type UsageHandler struct {
+	Quotas *services.QuotaService
+}
+
+func NewUsageHandler(quotas *services.QuotaService) *UsageHandler {
+	return &UsageHandler{Quotas: quotas}
+}
+
+// GetUsage shows how much the caller stores and how much they may store
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	usage, err := h.Quotas.Usage(requestUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+// SetQuota gives one user a quota other than the configured default
+func (h *UsageHandler) SetQuota(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var req SetQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	usage, err := h.Quotas.SetQuota(uint(id), req.QuotaBytes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// DeadLetter is a processing job that failed for good, kept with everything
+// needed to understand and replay it. Rows are written by the worker, or by
+// the job reaper when the worker died on the last attempt.
+type DeadLetter struct {
+	ID        uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	MeetingID uint           `gorm:"index;not null" json:"meeting_id"`
+	Payload   datatypes.JSON `gorm:"type:jsonb;not null" json:"payload"`
+	Error     string         `gorm:"type:text;not null" json:"error"`
+	Stack     *string        `gorm:"type:text" json:"stack"`
+	Attempts  int            `gorm:"not null" json:"attempts"`
+	WorkerID  *string        `gorm:"type:varchar(255)" json:"worker_id"`
+	FailedAt  time.Time      `gorm:"not null;index" json:"failed_at"`
+	// Set once the job has been queued again
+	ReplayedAt *time.Time `gorm:"index" json:"replayed_at"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+type StorageTier string
+
+const (
+	TierHot       StorageTier = "hot"
+	TierArchived  StorageTier = "archived"  // In the cold tier
+	TierRestoring StorageTier = "restoring" // Being copied back to the hot tier
+)
+
+// File is an object that was uploaded to storage through the API
+type File struct {
+	ID               uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Key              string `gorm:"type:varchar(500);uniqueIndex;not null" json:"key"`
+	OriginalFilename string `gorm:"type:varchar(255)" json:"original_filename"`
+	SizeBytes        int64  `json:"size_bytes"`
+	MimeType         string `gorm:"type:varchar(100)" json:"mime_type"`
+
+	// Objects are stored under their SHA-256, so identical uploads share one row.
+	// Direct and resumable uploads keep the key they were handed, since the
+	// client already holds it, and get their checksum once they are read back
+	// on completion. RefCount is the number of meetings using the file; at
+	// zero it is deleted.
+	Checksum *string `gorm:"type:char(64);uniqueIndex:idx_files_profile_checksum,priority:2" json:"checksum"`
+	RefCount int     `gorm:"not null;default:0" json:"ref_count"`
+
+	// Nullable until authentication lands
+	UploaderID *uint `gorm:"index" json:"uploader_id"`
+	// Zero for the default store. Checksums are only unique within a profile,
+	// since the same audio is stored once per bucket.
+	StorageProfileID uint `gorm:"not null;default:0;uniqueIndex:idx_files_profile_checksum,priority:1" json:"storage_profile_id"`
+
+	// Recordings that haven't been played for a while move to the cold tier
+	Tier               StorageTier `gorm:"type:varchar(20);not null;default:'hot';index" json:"tier"`
+	ArchivedAt         *time.Time  `json:"archived_at"`
+	RestoreRequestedAt *time.Time  `json:"restore_requested_at"`
+	LastAccessedAt     *time.Time  `json:"last_accessed_at"`
+
+	// Audio details extracted server-side on upload
+	Format          *string  `gorm:"type:varchar(20)" json:"format"`
+	DurationSeconds *float64 `json:"duration_seconds"`
+	SampleRate      *int     `json:"sample_rate"`
+	Channels        *int     `json:"channels"`
+	Bitrate         *int     `json:"bitrate"`
+
+	// Timestamps
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// FileGrant lets a user attach a file someone else uploaded. It is recorded
+// when their own upload of the same audio was deduplicated onto the file.
+type FileGrant struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	FileID    uint      `gorm:"not null;uniqueIndex:idx_file_grants_file_user,priority:1" json:"file_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_file_grants_file_user,priority:2" json:"user_id"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+type JobState string
+
+const (
+	JobStateQueued    JobState = "queued"
+	JobStateRunning   JobState = "running"
+	JobStateSucceeded JobState = "succeeded"
+	JobStateFailed    JobState = "failed"
+)
+
+type JobType string
+
+const (
+	// Transcribes and summarizes a meeting's recording
+	JobTypeProcessMeeting JobType = "process_meeting"
+)
+
+// Job is one attempt at processing a meeting. A job that is retried gets a
+// row per attempt, all with the same JobID.
+type Job struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	JobID     string `gorm:"type:varchar(600);index;not null" json:"job_id"` // The queue's identity for the job
+	MeetingID uint   `gorm:"index;not null" json:"meeting_id"`
+
+	Type           JobType  `gorm:"type:varchar(50);not null" json:"type"`
+	State          JobState `gorm:"type:varchar(20);not null;index" json:"state"`
+	Attempt        int      `gorm:"not null" json:"attempt"` // Starts at 1
+	WorkerID       *string  `gorm:"type:varchar(255)" json:"worker_id"`
+	PayloadVersion int      `gorm:"not null" json:"payload_version"` // The PipelineVersion the job was queued with
+
+	QueuedAt   *time.Time `json:"queued_at"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	// Stage name to StageTiming, filled in by the worker as each stage ends
+	StageTimings datatypes.JSON `gorm:"type:jsonb;not null;default:'{}'" json:"stage_timings"`
+	Error        *string        `gorm:"type:text" json:"error"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// StageTiming is how long one stage of a job took
+type StageTiming struct {
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+}
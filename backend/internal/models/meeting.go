@@ -10,9 +10,11 @@ type MeetingStatus string
 
 const (
 	StatusCreated    MeetingStatus = "created"
+	StatusQueued     MeetingStatus = "queued" // A job for the recording is waiting for a worker
 	StatusProcessing MeetingStatus = "processing"
 	StatusCompleted  MeetingStatus = "completed"
 	StatusFailed     MeetingStatus = "failed"
+	StatusDeleting   MeetingStatus = "deleting" // Row is kept until its stored objects are removed
 )
 
 type Meeting struct {
@@ -28,13 +30,26 @@ type Meeting struct {
 	ScheduledAt     *time.Time `json:"scheduled_at"`
 
 	// Recording Details
+	RecordingFileID          *uint   `gorm:"index" json:"recording_file_id"`
 	RecordingPath            *string `gorm:"type:varchar(500)" json:"recording_path"`
 	RecordingSizeBytes       *int64  `json:"recording_size_bytes"`
 	RecordingDurationSeconds *int    `json:"recording_duration_seconds" default:"0"`
+	RecordingMimeType        *string `gorm:"type:varchar(100)" json:"recording_mime_type"`
+	RecordingSampleRate      *int    `json:"recording_sample_rate"`
+	RecordingChannels        *int    `json:"recording_channels"`
+	RecordingBitrate         *int    `json:"recording_bitrate"`
+	// Tier of the recording's file, filled in when meetings are loaded
+	RecordingTier StorageTier `gorm:"-" json:"recording_tier,omitempty"`
 
-	// AI Results: Text is fine for now. If transcripts get >10MB, we move to S3.
-	Transcript *string `gorm:"type:text" json:"transcript"`
-	Summary    *string `gorm:"type:text" json:"summary"`
+	// AI Results. Transcripts above the offload threshold are kept in object
+	// storage under TranscriptKey instead, and Transcript is left empty.
+	// Either way the text is served by GET /meetings/:id/transcript.
+	Transcript          *string `gorm:"type:text" json:"transcript"`
+	TranscriptKey       *string `gorm:"type:varchar(500)" json:"transcript_key"`
+	TranscriptSizeBytes *int64  `json:"transcript_size_bytes"`
+	// Set when the meeting has a transcript in either place, filled in when meetings are loaded
+	HasTranscript bool    `gorm:"-" json:"has_transcript"`
+	Summary       *string `gorm:"type:text" json:"summary"`
 
 	// JSONB Fields
 	KeyPoints   datatypes.JSON `gorm:"type:jsonb" json:"key_points"`
@@ -42,10 +57,22 @@ type Meeting struct {
 
 	// Status details
 	Status MeetingStatus `gorm:"type:varchar(50);default:'created';index" json:"status"`
+	// Why processing last failed for good, cleared when the meeting is queued again
+	FailureReason *string `gorm:"type:text" json:"failure_reason"`
+	// Whether an update queued the recording for processing, only set on update responses
+	Enqueued *bool `gorm:"-" json:"enqueued,omitempty"`
 
 	// Nullable User ID for now
 	UserID *uint `gorm:"index" json:"user_id"`
 
+	// Retention: per-meeting overrides in days, and when each artifact was
+	// removed. A legal hold stops anything from expiring.
+	LegalHold               bool       `gorm:"not null;default:false" json:"legal_hold"`
+	RecordingRetentionDays  *int       `json:"recording_retention_days"`
+	TranscriptRetentionDays *int       `json:"transcript_retention_days"`
+	RecordingExpiredAt      *time.Time `json:"recording_expired_at"`
+	TranscriptExpiredAt     *time.Time `json:"transcript_expired_at"`
+
 	// Timestamps
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+type OutboxTopic string
+
+const (
+	TopicMeetingJob OutboxTopic = "meeting_job"
+)
+
+// OutboxMessage is a message written in the same transaction as the change
+// that caused it, and published by the relay afterwards. Rows are kept for a
+// while after dispatch so deliveries can be traced.
+type OutboxMessage struct {
+	ID      uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	Topic   OutboxTopic    `gorm:"type:varchar(50);not null" json:"topic"`
+	Payload datatypes.JSON `gorm:"type:jsonb;not null" json:"payload"`
+
+	// Failed publish attempts; the relay backs off until AvailableAt
+	Attempts    int       `gorm:"not null;default:0" json:"attempts"`
+	LastError   *string   `gorm:"type:text" json:"last_error"`
+	AvailableAt time.Time `gorm:"not null;index:idx_outbox_pending,where:dispatched_at IS NULL" json:"available_at"`
+	// Set once the message is published
+	DispatchedAt *time.Time `gorm:"index" json:"dispatched_at"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
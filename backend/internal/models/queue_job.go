@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// QueueJob is a job in the Postgres queue backend. A row lives from enqueue
+// until the job is acked; Consumer is set while a worker holds it.
+type QueueJob struct {
+	ID    uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	JobID string `gorm:"type:varchar(600);uniqueIndex;not null" json:"job_id"` // Deduplicates enqueues
+	// The job as sent to the worker; Attempts is kept in its own column
+	Payload  datatypes.JSON `gorm:"type:jsonb;not null" json:"payload"`
+	Attempts int            `gorm:"not null;default:0" json:"attempts"`
+
+	Consumer    *string    `gorm:"type:varchar(255);index" json:"consumer"`
+	HeartbeatAt *time.Time `json:"heartbeat_at"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
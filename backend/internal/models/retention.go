@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Retention applies at three levels: the server-wide defaults from config, a
+// RetentionPolicy per workspace (user, until workspaces exist), and overrides
+// on the meeting itself. The most specific level that sets a value wins.
+// A nil number of days inherits, zero keeps the artifact forever.
+
+// RetentionPolicy is a workspace's override of the global retention defaults
+type RetentionPolicy struct {
+	ID             uint `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID         uint `gorm:"uniqueIndex;not null" json:"user_id"`
+	RecordingDays  *int `json:"recording_days"`
+	TranscriptDays *int `json:"transcript_days"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+type RetentionArtifact string
+
+const (
+	ArtifactRecording  RetentionArtifact = "recording"
+	ArtifactTranscript RetentionArtifact = "transcript"
+)
+
+// RetentionAudit records one artifact removed because its retention expired
+type RetentionAudit struct {
+	ID        uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	MeetingID uint              `gorm:"index;not null" json:"meeting_id"`
+	UserID    *uint             `gorm:"index" json:"user_id"`
+	Artifact  RetentionArtifact `gorm:"type:varchar(20);not null" json:"artifact"`
+
+	// The stored object, for recordings. It is kept while another meeting
+	// still uses the same file, in which case ObjectDeleted is false.
+	ObjectKey     *string `gorm:"type:varchar(500)" json:"object_key"`
+	ObjectDeleted bool    `json:"object_deleted"`
+
+	// Which level the rule came from: "global", "workspace" or "meeting"
+	PolicySource  string    `gorm:"type:varchar(20);not null" json:"policy_source"`
+	RetentionDays int       `json:"retention_days"`
+	ExpiredAt     time.Time `json:"expired_at"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
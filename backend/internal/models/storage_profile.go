@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// StorageProfile sends a user's recordings to their own bucket, or to an
+// isolated prefix of the default one when Bucket is empty. Profiles are never
+// edited in place apart from their credentials: objects keep the profile they
+// were written with, so changing buckets means creating a new profile.
+type StorageProfile struct {
+	ID     uint `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID uint `gorm:"index;not null" json:"user_id"`
+
+	Driver   string `gorm:"type:varchar(20);not null" json:"driver"` // "s3" or "minio"
+	Bucket   string `gorm:"type:varchar(255)" json:"bucket"`
+	Endpoint string `gorm:"type:varchar(500)" json:"endpoint"`
+	Region   string `gorm:"type:varchar(50)" json:"region"`
+	Prefix   string `gorm:"type:varchar(255)" json:"prefix"`
+
+	// The secret key is sealed with STORAGE_CREDENTIALS_KEYS and never returned
+	AccessKey       string `gorm:"type:varchar(255)" json:"access_key"`
+	SecretKeyID     string `gorm:"type:varchar(64)" json:"-"`
+	SecretKeySealed []byte `json:"-"`
+	HasSecretKey    bool   `gorm:"-" json:"has_secret_key"`
+
+	// Set once new uploads stop using the profile; its objects stay readable
+	RetiredAt *time.Time `json:"retired_at"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// StorageUsage is how much of the default bucket an owner's files take up.
+// It is recomputed from the files table whenever a file is added, resized or
+// removed. OwnerID is the uploader, or 0 for anonymous uploads.
+type StorageUsage struct {
+	ID        uint  `gorm:"primaryKey;autoIncrement" json:"-"`
+	OwnerID   uint  `gorm:"uniqueIndex;not null" json:"owner_id"`
+	BytesUsed int64 `gorm:"not null;default:0" json:"bytes_used"`
+	FileCount int64 `gorm:"not null;default:0" json:"file_count"`
+
+	// Overrides the configured default quota; 0 means unlimited
+	QuotaBytes *int64 `json:"quota_bytes"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/handler"
+)
+
+func DeadLetterRoutes(router *gin.RouterGroup, deadLetterHandler *handler.DeadLetterHandler, adminAuth gin.HandlerFunc) {
+	deadLetters := router.Group("/admin/dead-letters", adminAuth)
+	deadLetters.GET("", deadLetterHandler.ListDeadLetters)
+	deadLetters.DELETE("", deadLetterHandler.PurgeDeadLetters)
+	deadLetters.POST("/replay", deadLetterHandler.ReplayDeadLetters)
+	deadLetters.GET("/:id", deadLetterHandler.GetDeadLetter)
+	deadLetters.DELETE("/:id", deadLetterHandler.DeleteDeadLetter)
+	deadLetters.POST("/:id/replay", deadLetterHandler.ReplayDeadLetter)
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/handler"
+)
+
+func JobRoutes(router *gin.RouterGroup, jobHandler *handler.JobHandler, workerAuth gin.HandlerFunc) {
+	jobs := router.Group("/jobs", workerAuth)
+	jobs.POST("", jobHandler.StartJob)
+	jobs.POST("/:id/stages", jobHandler.RecordJobStage)
+	jobs.POST("/:id/finish", jobHandler.FinishJob)
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/handler"
+)
+
+func LocalFileRoutes(router *gin.RouterGroup, localFileHandler *handler.LocalFileHandler) {
+	localRouter := router.Group("/file/local")
+	localRouter.GET("/*key", localFileHandler.ServeFile)
+	localRouter.PUT("/*key", localFileHandler.ReceiveFile)
+}
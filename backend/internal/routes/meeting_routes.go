@@ -5,11 +5,17 @@ import (
 	"github.com/jaykapade/meeting-assistant/backend/internal/handler"
 )
 
-func MeetingRoutes(router *gin.RouterGroup, meetingHandler *handler.MeetingHandler) {
+func MeetingRoutes(router *gin.RouterGroup, meetingHandler *handler.MeetingHandler, workerAuth gin.HandlerFunc) {
 	meetingsRouter := router.Group("/meetings")
 	meetingsRouter.POST("", meetingHandler.CreateMeeting)
 	meetingsRouter.GET("/:id", meetingHandler.GetMeeting)
 	meetingsRouter.GET("", meetingHandler.GetAllMeetings)
 	meetingsRouter.PUT("/:id", meetingHandler.UpdateMeeting)
 	meetingsRouter.DELETE("/:id", meetingHandler.DeleteMeeting)
+	meetingsRouter.POST("/:id/recording", meetingHandler.AttachRecording)
+	meetingsRouter.GET("/:id/recording", meetingHandler.StreamRecording)
+	meetingsRouter.GET("/:id/transcript", meetingHandler.GetTranscript)
+	meetingsRouter.PUT("/:id/transcript", workerAuth, meetingHandler.PutTranscript)
+	meetingsRouter.GET("/:id/jobs", meetingHandler.GetMeetingJobs)
+	meetingsRouter.GET("/:id/events", meetingHandler.StreamMeetingEvents)
 }
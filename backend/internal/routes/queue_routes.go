@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/handler"
+)
+
+func QueueRoutes(router *gin.RouterGroup, queueHandler *handler.QueueHandler, adminAuth gin.HandlerFunc) {
+	router.GET("/admin/queue", adminAuth, queueHandler.GetQueue)
+}
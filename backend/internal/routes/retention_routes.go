@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/handler"
+)
+
+func RetentionRoutes(router *gin.RouterGroup, retentionHandler *handler.RetentionHandler, adminAuth gin.HandlerFunc) {
+	router.GET("/retention", retentionHandler.GetPolicy)
+	// A workspace policy expires every recording of the user named in
+	// X-User-ID, which anyone can set, so only an operator may change it
+	router.PUT("/retention", adminAuth, retentionHandler.SetPolicy)
+
+	meetingsRouter := router.Group("/meetings")
+	meetingsRouter.GET("/:id/retention", retentionHandler.GetMeetingRetention)
+	meetingsRouter.PUT("/:id/retention", retentionHandler.SetMeetingRetention)
+	meetingsRouter.PUT("/:id/legal-hold", adminAuth, retentionHandler.SetLegalHold)
+}
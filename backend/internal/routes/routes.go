@@ -6,8 +6,21 @@ import (
 )
 
 type RouteConfig struct {
-	MeetingHandler *handler.MeetingHandler
-	UploadHandler  *handler.UploadHandler
+	MeetingHandler    *handler.MeetingHandler
+	UploadHandler     *handler.UploadHandler
+	TusHandler        *handler.TusHandler
+	StorageHandler    *handler.StorageHandler
+	RetentionHandler  *handler.RetentionHandler
+	UsageHandler      *handler.UsageHandler
+	DeadLetterHandler *handler.DeadLetterHandler
+	QueueHandler      *handler.QueueHandler
+	JobHandler        *handler.JobHandler
+	// Guards the operator endpoints under /admin
+	AdminAuth gin.HandlerFunc
+	// Guards the endpoints the AI worker writes results through
+	WorkerAuth gin.HandlerFunc
+	// Only set when the local storage driver is in use
+	LocalFileHandler *handler.LocalFileHandler
 }
 
 func RegisterRoutes(router *gin.Engine, cfg *RouteConfig) {
@@ -19,7 +32,17 @@ func RegisterRoutes(router *gin.Engine, cfg *RouteConfig) {
 		})
 	})
 
-	MeetingRoutes(api, cfg.MeetingHandler)
+	MeetingRoutes(api, cfg.MeetingHandler, cfg.WorkerAuth)
 	UploadRoutes(api, cfg.UploadHandler)
+	TusRoutes(api, cfg.TusHandler)
+	if cfg.LocalFileHandler != nil {
+		LocalFileRoutes(api, cfg.LocalFileHandler)
+	}
+	StorageRoutes(api, cfg.StorageHandler, cfg.AdminAuth)
+	RetentionRoutes(api, cfg.RetentionHandler, cfg.AdminAuth)
+	UsageRoutes(api, cfg.UsageHandler, cfg.AdminAuth)
+	DeadLetterRoutes(api, cfg.DeadLetterHandler, cfg.AdminAuth)
+	QueueRoutes(api, cfg.QueueHandler, cfg.AdminAuth)
+	JobRoutes(api, cfg.JobHandler, cfg.WorkerAuth)
 
 }
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/handler"
+)
+
+func StorageRoutes(router *gin.RouterGroup, storageHandler *handler.StorageHandler, adminAuth gin.HandlerFunc) {
+	storageRouter := router.Group("/storage")
+	storageRouter.GET("/profile", storageHandler.GetProfile)
+	// X-User-ID can be set by anyone, so only an operator may point a user's
+	// uploads at a bucket until requests are authenticated
+	storageRouter.POST("/profile", adminAuth, storageHandler.CreateProfile)
+	storageRouter.PUT("/profile/credentials", adminAuth, storageHandler.UpdateCredentials)
+	storageRouter.DELETE("/profile", adminAuth, storageHandler.RetireProfile)
+	if storageHandler.Mirror != nil {
+		// Errors name other users' objects and carry raw provider messages
+		storageRouter.GET("/replication", adminAuth, storageHandler.ReplicationStatus)
+	}
+}
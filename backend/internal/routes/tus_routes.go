@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/handler"
+)
+
+func TusRoutes(router *gin.RouterGroup, tusHandler *handler.TusHandler) {
+	tusRouter := router.Group("/file/uploads")
+	tusRouter.OPTIONS("", tusHandler.Options)
+	tusRouter.POST("", tusHandler.CreateUpload)
+	tusRouter.OPTIONS("/:id", tusHandler.Options)
+	tusRouter.HEAD("/:id", tusHandler.GetOffset)
+	tusRouter.PATCH("/:id", tusHandler.PatchUpload)
+	tusRouter.DELETE("/:id", tusHandler.DeleteUpload)
+}
@@ -7,6 +7,10 @@ import (
 
 func UploadRoutes(router *gin.RouterGroup, uploadHandler *handler.UploadHandler) {
 	uploadRouter := router.Group("/file")
+	uploadRouter.GET("", uploadHandler.GetAllFiles)
+	uploadRouter.GET("/:id", uploadHandler.GetFile)
 	uploadRouter.POST("/upload", uploadHandler.UploadFile)
-	uploadRouter.GET("/download/:file_id", uploadHandler.DownloadFile)
+	uploadRouter.POST("/presign", uploadHandler.PresignUpload)
+	// Keys of recordings in a storage profile contain slashes
+	uploadRouter.GET("/download/*file_id", uploadHandler.DownloadFile)
 }
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jaykapade/meeting-assistant/backend/internal/handler"
+)
+
+func UsageRoutes(router *gin.RouterGroup, usageHandler *handler.UsageHandler, adminAuth gin.HandlerFunc) {
+	router.GET("/usage", usageHandler.GetUsage)
+	router.PUT("/admin/users/:id/quota", adminAuth, usageHandler.SetQuota)
+}
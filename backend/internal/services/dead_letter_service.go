@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrDeadLetterReplayed = errors.New("dead letter was already replayed")
+	// The meeting is gone, no longer failed, or its recording has expired
+	ErrNotReplayable = errors.New("meeting is not failed or can no longer be processed")
+)
+
+// DeadLetterFilter narrows the dead letters listed or purged
+type DeadLetterFilter struct {
+	MeetingID *uint
+	Replayed  *bool
+	Before    *time.Time // Failed before this time
+}
+
+func (f DeadLetterFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.MeetingID != nil {
+		query = query.Where("meeting_id = ?", *f.MeetingID)
+	}
+	if f.Replayed != nil {
+		if *f.Replayed {
+			query = query.Where("replayed_at IS NOT NULL")
+		} else {
+			query = query.Where("replayed_at IS NULL")
+		}
+	}
+	if f.Before != nil {
+		query = query.Where("failed_at < ?", *f.Before)
+	}
+	return query
+}
+
+// ReplayResult is the outcome of replaying one dead letter in a bulk replay
+type ReplayResult struct {
+	ID        uint   `json:"id"`
+	MeetingID uint   `json:"meeting_id"`
+	Replayed  bool   `json:"replayed"`
+	Error     string `json:"error,omitempty"`
+}
+
+type DeadLetterService struct {
+	DB     *gorm.DB
+	Outbox *OutboxRelay
+	Events *MeetingEventService
+}
+
+func NewDeadLetterService(db *gorm.DB, outbox *OutboxRelay, events *MeetingEventService) *DeadLetterService {
+	return &DeadLetterService{DB: db, Outbox: outbox, Events: events}
+}
+
+// List returns dead letters newest first, without their stack traces, and
+// how many match the filter in total
+func (s *DeadLetterService) List(filter DeadLetterFilter, limit, offset int) ([]models.DeadLetter, int64, error) {
+	var total int64
+	if err := filter.apply(s.DB.Model(&models.DeadLetter{})).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	letters := []models.DeadLetter{}
+	err := filter.apply(s.DB.Omit("stack")).
+		Order("failed_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&letters).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return letters, total, nil
+}
+
+func (s *DeadLetterService) Get(id uint) (*models.DeadLetter, error) {
+	var letter models.DeadLetter
+	if err := s.DB.First(&letter, id).Error; err != nil {
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// Replay queues the meeting of a dead letter again with fresh attempts. Only
+// a meeting that is still failed is replayed, so an old letter can't reprocess
+// one that has since completed. It goes back to "queued" and its failure
+// reason is cleared.
+func (s *DeadLetterService) Replay(ctx context.Context, id uint) (*models.DeadLetter, error) {
+	var letter models.DeadLetter
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&letter, id).Error
+		if err != nil {
+			return err
+		}
+		if letter.ReplayedAt != nil {
+			return ErrDeadLetterReplayed
+		}
+
+		var meeting models.Meeting
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ?", models.StatusFailed).
+			First(&meeting, letter.MeetingID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotReplayable
+		}
+		if err != nil {
+			return err
+		}
+		if meeting.RecordingPath == nil || *meeting.RecordingPath == "" {
+			return ErrNotReplayable
+		}
+
+		now := time.Now()
+		letter.ReplayedAt = &now
+		if err := tx.Model(&letter).Update("replayed_at", now).Error; err != nil {
+			return err
+		}
+		err = tx.Model(&meeting).Updates(map[string]interface{}{
+			"status":         models.StatusQueued,
+			"failure_reason": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return writeMeetingJob(tx, &meeting)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Outbox.Notify()
+	s.Events.PublishStatus(letter.MeetingID, models.StatusQueued, "")
+	return &letter, nil
+}
+
+// ReplayMany replays each dead letter in turn. With no IDs it replays every
+// one that hasn't been replayed yet.
+func (s *DeadLetterService) ReplayMany(ctx context.Context, ids []uint) ([]ReplayResult, error) {
+	var letters []models.DeadLetter
+	query := s.DB.WithContext(ctx).Select("id", "meeting_id").Order("id")
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	} else {
+		query = query.Where("replayed_at IS NULL")
+	}
+	if err := query.Find(&letters).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]ReplayResult, 0, len(letters))
+	for _, letter := range letters {
+		result := ReplayResult{ID: letter.ID, MeetingID: letter.MeetingID}
+		if _, err := s.Replay(ctx, letter.ID); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Replayed = true
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (s *DeadLetterService) Delete(id uint) error {
+	result := s.DB.Delete(&models.DeadLetter{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Purge deletes every dead letter matching the filter and returns how many
+func (s *DeadLetterService) Purge(filter DeadLetterFilter) (int64, error) {
+	result := filter.apply(s.DB.Session(&gorm.Session{AllowGlobalUpdate: true})).Delete(&models.DeadLetter{})
+	return result.RowsAffected, result.Error
+}
+
+// deadLetter fails a meeting for good and keeps its job for inspection. Must
+// run inside a transaction.
+func deadLetter(tx *gorm.DB, job MeetingJob, payload string, reason, workerID string) error {
+	err := tx.Model(&models.Meeting{}).
+		Where("id = ? AND status IN ?", job.ID, []models.MeetingStatus{models.StatusCreated, models.StatusQueued, models.StatusProcessing}).
+		Updates(map[string]interface{}{
+			"status":         models.StatusFailed,
+			"failure_reason": reason,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark meeting %d as failed: %w", job.ID, err)
+	}
+
+	return tx.Create(&models.DeadLetter{
+		MeetingID: job.ID,
+		Payload:   datatypes.JSON(payload),
+		Error:     reason,
+		Attempts:  job.Attempts + 1,
+		WorkerID:  &workerID,
+		FailedAt:  time.Now(),
+	}).Error
+}
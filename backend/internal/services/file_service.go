@@ -0,0 +1,248 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/audio"
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUnknownFile  = errors.New("file was not uploaded")
+	ErrFileNotOwned = errors.New("file belongs to another user")
+)
+
+type FileService struct {
+	DB *gorm.DB
+}
+
+func NewFileService(db *gorm.DB) *FileService {
+	return &FileService{DB: db}
+}
+
+// CreateFile records an upload and counts it towards the uploader's usage
+func (s *FileService) CreateFile(file *models.File) (*models.File, error) {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(file).Error; err != nil {
+			return err
+		}
+		return recomputeUsage(tx, usageOwner(file.UploaderID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (s *FileService) GetFile(id uint) (*models.File, error) {
+	var file models.File
+	err := s.DB.First(&file, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// GetAllFiles lists the files a user uploaded, or the anonymous uploads for nil
+// TODO: Add pagination
+func (s *FileService) GetAllFiles(uploaderID *uint) ([]models.File, error) {
+	var files []models.File
+	query := s.DB.Order("created_at DESC").Where("uploader_id IS NULL")
+	if uploaderID != nil {
+		query = s.DB.Order("created_at DESC").Where("uploader_id = ?", *uploaderID)
+	}
+	err := query.Find(&files).Error
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// GetFileMeetingIDs lists the meetings a file is attached to
+func (s *FileService) GetFileMeetingIDs(id uint) ([]uint, error) {
+	var ids []uint
+	err := s.DB.Model(&models.Meeting{}).
+		Where("recording_file_id = ? AND status <> ?", id, models.StatusDeleting).
+		Order("id").
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ResolveRecording finds the file a meeting wants to use, by ID or by key,
+// and checks the caller is allowed to use it: they uploaded it, or uploaded
+// the same audio and were handed it back. Anonymous uploads are shared.
+func (s *FileService) ResolveRecording(fileID *uint, key *string, userID *uint) (*models.File, error) {
+	var file *models.File
+	var err error
+	if fileID != nil {
+		file, err = s.GetFile(*fileID)
+	} else {
+		file, err = s.GetFileByKey(*key)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnknownFile
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if file.UploaderID == nil || (userID != nil && *userID == *file.UploaderID) {
+		return file, nil
+	}
+	if userID == nil {
+		return nil, ErrFileNotOwned
+	}
+	var grants int64
+	err = s.DB.Model(&models.FileGrant{}).Where("file_id = ? AND user_id = ?", file.ID, *userID).Count(&grants).Error
+	if err != nil {
+		return nil, err
+	}
+	if grants == 0 {
+		return nil, ErrFileNotOwned
+	}
+	return file, nil
+}
+
+// GrantFile lets a user whose upload was deduplicated onto another user's
+// file attach it to their meetings
+func (s *FileService) GrantFile(file *models.File, userID *uint) error {
+	if userID == nil || file.UploaderID == nil || *userID == *file.UploaderID {
+		return nil
+	}
+	grant := models.FileGrant{FileID: file.ID, UserID: *userID}
+	return s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&grant).Error
+}
+
+// UpdateFileSize records the size storage reports once a direct upload lands
+func (s *FileService) UpdateFileSize(file *models.File, size int64) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(file).Update("size_bytes", size).Error; err != nil {
+			return err
+		}
+		return recomputeUsage(tx, usageOwner(file.UploaderID))
+	})
+}
+
+// RecordAudioMetadata stores the details and checksum measured once a direct
+// upload landed. It fails on the unique checksum if identical audio is
+// already stored in the profile.
+func (s *FileService) RecordAudioMetadata(file *models.File, metadata *audio.Metadata, checksum string) error {
+	file.Checksum = &checksum
+	file.MimeType = metadata.MimeType
+	file.Format = (*string)(&metadata.Format)
+	file.DurationSeconds = &metadata.DurationSeconds
+	file.SampleRate = &metadata.SampleRate
+	file.Channels = &metadata.Channels
+	file.Bitrate = &metadata.Bitrate
+	return s.DB.Model(file).Updates(map[string]interface{}{
+		"checksum":         file.Checksum,
+		"mime_type":        file.MimeType,
+		"format":           file.Format,
+		"duration_seconds": file.DurationSeconds,
+		"sample_rate":      file.SampleRate,
+		"channels":         file.Channels,
+		"bitrate":          file.Bitrate,
+	}).Error
+}
+
+// DiscardFile drops the row of a rejected upload and reports whether it went,
+// so the caller deletes the object. Files a meeting uses are kept.
+func (s *FileService) DiscardFile(file *models.File) (bool, error) {
+	var discarded bool
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("ref_count = 0").Delete(file)
+		if result.Error != nil {
+			return result.Error
+		}
+		discarded = result.RowsAffected > 0
+		if !discarded {
+			return nil
+		}
+		if err := deleteFileGrants(tx, file.ID); err != nil {
+			return err
+		}
+		return recomputeUsage(tx, usageOwner(file.UploaderID))
+	})
+	return discarded, err
+}
+
+func (s *FileService) GetFileByKey(key string) (*models.File, error) {
+	var file models.File
+	err := s.DB.Where("key = ?", key).First(&file).Error
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// GetFileByChecksum finds identical audio already stored in the same profile
+func (s *FileService) GetFileByChecksum(profileID uint, checksum string) (*models.File, error) {
+	var file models.File
+	err := s.DB.Where("storage_profile_id = ? AND checksum = ?", profileID, checksum).First(&file).Error
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// GetProcessedMeeting finds a finished meeting of the user for the recording, so
+// a duplicate upload can reuse its transcript instead of running Whisper again
+func (s *FileService) GetProcessedMeeting(key string, userID *uint) (*models.Meeting, error) {
+	var meeting models.Meeting
+	query := s.DB.Where("user_id IS NULL")
+	if userID != nil {
+		query = s.DB.Where("user_id IS NULL OR user_id = ?", *userID)
+	}
+	err := query.
+		Where("recording_path = ? AND status = ?", key, models.StatusCompleted).
+		Where("transcript IS NOT NULL OR transcript_key IS NOT NULL").
+		Order("updated_at DESC").
+		First(&meeting).Error
+	if err != nil {
+		return nil, err
+	}
+	return &meeting, nil
+}
+
+// retainFile records one more meeting using the file. Keys without a file row
+// predate the files table and are simply not counted.
+func retainFile(tx *gorm.DB, key string) error {
+	return tx.Model(&models.File{}).
+		Where("key = ?", key).
+		UpdateColumn("ref_count", gorm.Expr("ref_count + 1")).Error
+}
+
+// releaseFile drops one reference and reports whether the object is now unused
+// and should be deleted from storage. Must run inside a transaction.
+func releaseFile(tx *gorm.DB, key string) (bool, error) {
+	var file models.File
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if file.RefCount > 1 {
+		err := tx.Model(&file).UpdateColumn("ref_count", gorm.Expr("ref_count - 1")).Error
+		return false, err
+	}
+	if err := tx.Delete(&file).Error; err != nil {
+		return false, err
+	}
+	if err := deleteFileGrants(tx, file.ID); err != nil {
+		return false, err
+	}
+	return true, recomputeUsage(tx, usageOwner(file.UploaderID))
+}
+
+// deleteFileGrants drops the grants of a file whose row was deleted
+func deleteFileGrants(tx *gorm.DB, fileID uint) error {
+	return tx.Where("file_id = ?", fileID).Delete(&models.FileGrant{}).Error
+}
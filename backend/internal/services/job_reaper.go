@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Rolls back the attempt history when a reaped job turns out to be acked
+var errJobAcked = errors.New("job was acked")
+
+// ReapReport summarises one reaper run
+type ReapReport struct {
+	DeadWorkers int `json:"dead_workers"`
+	Requeued    int `json:"requeued"`
+	Failed      int `json:"failed"` // Jobs out of attempts, moved to the dead letters
+}
+
+// JobReaper returns the jobs of workers that stopped sending heartbeats to the
+// queue, and fails the ones that have used up their attempts
+type JobReaper struct {
+	DB    *gorm.DB
+	Queue Queue
+	// A worker is considered dead once its heartbeat is older than this
+	VisibilityTimeout time.Duration
+	// Used for jobs that don't carry their own limit
+	MaxAttempts int
+	Events      *MeetingEventService
+}
+
+func NewJobReaper(db *gorm.DB, queue Queue, visibilityTimeout time.Duration, maxAttempts int, events *MeetingEventService) *JobReaper {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultJobMaxAttempts
+	}
+	return &JobReaper{DB: db, Queue: queue, VisibilityTimeout: visibilityTimeout, MaxAttempts: maxAttempts, Events: events}
+}
+
+// Reap takes back the deliveries of every worker whose heartbeat is too old
+func (r *JobReaper) Reap(ctx context.Context) (*ReapReport, error) {
+	report := &ReapReport{}
+
+	stalled, err := r.Queue.Stalled(ctx, r.VisibilityTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	deadWorkers := map[string]bool{}
+	for _, delivery := range stalled {
+		deadWorkers[delivery.Consumer] = true
+		r.reapJob(ctx, delivery, report)
+	}
+	report.DeadWorkers = len(deadWorkers)
+	return report, nil
+}
+
+// reapJob puts one job of a dead worker back on the queue, or fails its
+// meeting if that was the last attempt
+func (r *JobReaper) reapJob(ctx context.Context, delivery *Delivery, report *ReapReport) {
+	job := delivery.Job
+	maxAttempts := job.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = r.MaxAttempts
+	}
+
+	if job.Attempts+1 >= maxAttempts {
+		payload, err := json.Marshal(job)
+		if err != nil {
+			log.Printf("Failed to marshal job of meeting %d: %v", job.ID, err)
+			return
+		}
+
+		// Fail the meeting before dropping the job, so a crash in between only
+		// means the job is dead-lettered again on the next run
+		reason := fmt.Sprintf("worker %s stopped responding during attempt %d", delivery.Consumer, job.Attempts+1)
+		err = r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := abandonAttempt(tx, job, reason, false); err != nil {
+				return err
+			}
+			return deadLetter(tx, job, string(payload), reason, delivery.Consumer)
+		})
+		if err != nil {
+			log.Printf("Failed to dead-letter job of meeting %d: %v", job.ID, err)
+			return
+		}
+		if err := r.Queue.Ack(ctx, delivery); err != nil {
+			log.Printf("Failed to drop job of meeting %d: %v", job.ID, err)
+			return
+		}
+		log.Printf("Job of meeting %d failed after %d attempts", job.ID, job.Attempts+1)
+		r.Events.PublishStatus(job.ID, models.StatusFailed, reason)
+		report.Failed++
+		return
+	}
+
+	// The next attempt is recorded before the job goes back on the queue, so
+	// a worker that takes it at once finds it
+	reason := fmt.Sprintf("worker %s stopped responding", delivery.Consumer)
+	requeued := false
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := abandonAttempt(tx, job, reason, true); err != nil {
+			return err
+		}
+		var err error
+		if requeued, err = r.Queue.Nack(ctx, delivery); err != nil {
+			return err
+		}
+		if !requeued {
+			// The worker finished the job after all
+			return errJobAcked
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errJobAcked) {
+		log.Printf("Failed to requeue job of meeting %d: %v", job.ID, err)
+		return
+	}
+	if requeued {
+		report.Requeued++
+	}
+}
+
+// RunReaper calls Reap every interval until ctx is done
+func (r *JobReaper) RunReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := r.Reap(ctx)
+			if err != nil {
+				log.Printf("Job reaper failed: %v", err)
+				continue
+			}
+			if report.Requeued > 0 || report.Failed > 0 {
+				log.Printf("Job reaper found %d dead workers, requeued %d jobs and failed %d",
+					report.DeadWorkers, report.Requeued, report.Failed)
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrJobFinished = errors.New("job has already finished")
+
+// JobService keeps the history of processing attempts. The relay records an
+// attempt when it queues a job, and the worker reports its progress through
+// the jobs API.
+type JobService struct {
+	DB *gorm.DB
+}
+
+func NewJobService(db *gorm.DB) *JobService {
+	return &JobService{DB: db}
+}
+
+// ListForMeeting returns every attempt at processing the meeting, newest first
+func (s *JobService) ListForMeeting(meetingID uint) ([]models.Job, error) {
+	jobs := []models.Job{}
+	err := s.DB.Where("meeting_id = ?", meetingID).Order("id DESC").Find(&jobs).Error
+	return jobs, err
+}
+
+// Start marks the next attempt at a job as taken by a worker. The attempt was
+// usually recorded when it was queued; one is added if not, e.g. for jobs
+// queued before attempts were tracked. A worker that restarts during an
+// attempt gets the same one back.
+func (s *JobService) Start(job MeetingJob, workerID string) (*models.Job, error) {
+	var row models.Job
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("job_id = ? AND attempt = ? AND state IN ?", job.JobID, job.Attempts+1,
+				[]models.JobState{models.JobStateQueued, models.JobStateRunning}).
+			Order("id DESC").
+			First(&row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			row = newJobAttempt(job, job.Attempts+1, nil)
+			err = tx.Create(&row).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		row.State = models.JobStateRunning
+		row.WorkerID = &workerID
+		row.StartedAt = &now
+		return tx.Model(&row).Updates(map[string]interface{}{
+			"state":      row.State,
+			"worker_id":  workerID,
+			"started_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// RecordStage adds the timing of a stage the worker finished
+func (s *JobService) RecordStage(id uint, stage string, timing models.StageTiming) (*models.Job, error) {
+	value, err := json.Marshal(timing)
+	if err != nil {
+		return nil, err
+	}
+
+	result := s.DB.Model(&models.Job{}).
+		Where("id = ? AND state = ?", id, models.JobStateRunning).
+		Update("stage_timings", gorm.Expr("stage_timings || jsonb_build_object(?::text, ?::jsonb)", stage, string(value)))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var row models.Job
+	if err := s.DB.First(&row, id).Error; err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrJobFinished
+	}
+	return &row, nil
+}
+
+// Finish records how an attempt ended. A failed attempt that will be retried
+// also gets the next attempt queued, so its history has no gap.
+func (s *JobService) Finish(id uint, state models.JobState, reason *string, retry bool) (*models.Job, error) {
+	var row models.Job
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&row, id).Error
+		if err != nil {
+			return err
+		}
+		return finishAttempt(tx, &row, state, reason, retry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// recordQueued adds the attempt a job was just queued for
+func recordQueued(tx *gorm.DB, job MeetingJob) error {
+	now := time.Now()
+	row := newJobAttempt(job, job.Attempts+1, &now)
+	return tx.Create(&row).Error
+}
+
+// abandonAttempt fails the attempt a worker died during, if it was recorded.
+// Must run inside a transaction.
+func abandonAttempt(tx *gorm.DB, job MeetingJob, reason string, retry bool) error {
+	var row models.Job
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("job_id = ? AND attempt = ? AND state IN ?", job.JobID, job.Attempts+1,
+			[]models.JobState{models.JobStateQueued, models.JobStateRunning}).
+		Order("id DESC").
+		First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return finishAttempt(tx, &row, models.JobStateFailed, &reason, retry)
+}
+
+func finishAttempt(tx *gorm.DB, row *models.Job, state models.JobState, reason *string, retry bool) error {
+	if row.State != models.JobStateQueued && row.State != models.JobStateRunning {
+		return ErrJobFinished
+	}
+
+	now := time.Now()
+	row.State = state
+	row.Error = reason
+	row.FinishedAt = &now
+	err := tx.Model(row).Updates(map[string]interface{}{
+		"state":       state,
+		"error":       reason,
+		"finished_at": now,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to finish job %d: %w", row.ID, err)
+	}
+
+	if state != models.JobStateFailed || !retry {
+		return nil
+	}
+	next := models.Job{
+		JobID:          row.JobID,
+		MeetingID:      row.MeetingID,
+		Type:           row.Type,
+		State:          models.JobStateQueued,
+		Attempt:        row.Attempt + 1,
+		PayloadVersion: row.PayloadVersion,
+		QueuedAt:       &now,
+		StageTimings:   datatypes.JSON("{}"),
+	}
+	return tx.Create(&next).Error
+}
+
+func newJobAttempt(job MeetingJob, attempt int, queuedAt *time.Time) models.Job {
+	return models.Job{
+		JobID:          job.JobID,
+		MeetingID:      job.ID,
+		Type:           models.JobTypeProcessMeeting,
+		State:          models.JobStateQueued,
+		Attempt:        attempt,
+		PayloadVersion: job.PipelineVersion,
+		QueuedAt:       queuedAt,
+		StageTimings:   datatypes.JSON("{}"),
+	}
+}
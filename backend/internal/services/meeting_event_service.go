@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// A meeting's events are published on its channel and kept on a short log, so
+// a client that reconnects can catch up on what it missed. The key names
+// match the Python worker.
+const (
+	meetingEventsPrefix = "meeting_events:"
+	meetingEventsKept   = 200
+	meetingEventsTTL    = 24 * time.Hour
+)
+
+const (
+	EventProgress = "progress"
+	EventStatus   = "status"
+)
+
+// publishEventScript gives the event an ID, logs it and publishes it. IDs are
+// millisecond timestamps bumped past the last one, so they keep increasing
+// even after the log expires.
+var publishEventScript = redis.NewScript(`
+local now = redis.call('TIME')
+local id = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+local last = tonumber(redis.call('GET', KEYS[1]) or '0')
+if id <= last then
+	id = last + 1
+end
+redis.call('SET', KEYS[1], id, 'EX', ARGV[3])
+
+local event = cjson.decode(ARGV[1])
+event['id'] = id
+local payload = cjson.encode(event)
+redis.call('RPUSH', KEYS[2], payload)
+redis.call('LTRIM', KEYS[2], -tonumber(ARGV[2]), -1)
+redis.call('EXPIRE', KEYS[2], ARGV[3])
+redis.call('PUBLISH', KEYS[3], payload)
+return id
+`)
+
+// MeetingEvent is a step in processing a meeting: the worker's progress
+// through a stage, or a change of status
+type MeetingEvent struct {
+	ID      int64                `json:"id"`
+	Type    string               `json:"type"`
+	Stage   string               `json:"stage,omitempty"` // e.g. "downloading", "transcribing"
+	Percent *int                 `json:"percent,omitempty"`
+	Status  models.MeetingStatus `json:"status,omitempty"`
+	Message string               `json:"message,omitempty"`
+	At      time.Time            `json:"at"`
+}
+
+type MeetingEventService struct {
+	Client *redis.Client
+}
+
+func NewMeetingEventService(addr string) *MeetingEventService {
+	client := redis.NewClient(&redis.Options{
+		Addr: addr, // e.g., "localhost:6379"
+	})
+	return &MeetingEventService{Client: client}
+}
+
+func meetingEventsChannel(meetingID uint) string {
+	return fmt.Sprintf("%s%d", meetingEventsPrefix, meetingID)
+}
+
+// Publish sends an event to everyone following the meeting and returns its ID
+func (s *MeetingEventService) Publish(ctx context.Context, meetingID uint, event MeetingEvent) (int64, error) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	channel := meetingEventsChannel(meetingID)
+	keys := []string{channel + ":seq", channel + ":log", channel}
+	return publishEventScript.Run(ctx, s.Client, keys, payload, meetingEventsKept, int(meetingEventsTTL.Seconds())).Int64()
+}
+
+// PublishStatus announces a status change. Events are best effort, so
+// failures are only logged; a nil service publishes nothing.
+func (s *MeetingEventService) PublishStatus(meetingID uint, status models.MeetingStatus, message string) {
+	if s == nil {
+		return
+	}
+	event := MeetingEvent{Type: EventStatus, Status: status, Message: message}
+	if _, err := s.Publish(context.Background(), meetingID, event); err != nil {
+		log.Printf("Failed to publish status of meeting %d: %v", meetingID, err)
+	}
+}
+
+// Subscribe follows the meeting's channel. Subscribe before reading the log
+// with Since, so no event falls between the two.
+func (s *MeetingEventService) Subscribe(ctx context.Context, meetingID uint) (*redis.PubSub, error) {
+	pubsub := s.Client.Subscribe(ctx, meetingEventsChannel(meetingID))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	return pubsub, nil
+}
+
+// Since returns the logged events after lastID, oldest first, as published
+func (s *MeetingEventService) Since(ctx context.Context, meetingID uint, lastID int64) ([]string, error) {
+	logged, err := s.Client.LRange(ctx, meetingEventsChannel(meetingID)+":log", 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []string
+	for _, payload := range logged {
+		if EventID(payload) > lastID {
+			events = append(events, payload)
+		}
+	}
+	return events, nil
+}
+
+// EventID reads the ID of a published event, or 0 if it has none
+func EventID(payload string) int64 {
+	var event struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return 0
+	}
+	return event.ID
+}
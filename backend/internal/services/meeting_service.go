@@ -1,22 +1,54 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
 	"github.com/jaykapade/meeting-assistant/backend/internal/models"
 	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
 	"gorm.io/gorm"
 )
 
+// ErrDeletionPending means the meeting is hidden but its objects still need removing
+var ErrDeletionPending = errors.New("meeting deletion pending")
+
 type MeetingService struct {
-	DB    *gorm.DB
-	Store storage.Provider
+	DB     *gorm.DB
+	Store  storage.Provider
+	Outbox *OutboxRelay
+	Events *MeetingEventService
+}
+
+func NewMeetingService(db *gorm.DB, store storage.Provider, outbox *OutboxRelay, events *MeetingEventService) *MeetingService {
+	return &MeetingService{DB: db, Store: store, Outbox: outbox, Events: events}
 }
 
-func NewMeetingService(db *gorm.DB, store storage.Provider) *MeetingService {
-	return &MeetingService{DB: db, Store: store}
+// visible excludes meetings that are waiting for their storage to be cleaned up
+func (s *MeetingService) visible() *gorm.DB {
+	return s.DB.Where("status <> ?", models.StatusDeleting)
 }
 
 func (s *MeetingService) CreateMeeting(meeting *models.Meeting) (*models.Meeting, error) {
-	err := s.DB.Create(meeting).Error
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(meeting).Error; err != nil {
+			return err
+		}
+		if meeting.RecordingPath == nil {
+			return nil
+		}
+
+		if err := retainFile(tx, *meeting.RecordingPath); err != nil {
+			return err
+		}
+		if columns := s.recordingColumns(*meeting.RecordingPath); columns != nil {
+			return tx.Model(meeting).Updates(columns).Error
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -25,43 +57,387 @@ func (s *MeetingService) CreateMeeting(meeting *models.Meeting) (*models.Meeting
 
 func (s *MeetingService) GetMeeting(id uint) (*models.Meeting, error) {
 	var meeting models.Meeting
-	err := s.DB.First(&meeting, id).Error
+	err := s.visible().First(&meeting, id).Error
 	if err != nil {
 		return nil, err
 	}
+	if err := s.fillRecordingTiers(&meeting); err != nil {
+		return nil, err
+	}
+	meeting.HasTranscript = meeting.Transcript != nil || meeting.TranscriptKey != nil
 	return &meeting, nil
 }
 
-func (s *MeetingService) GetAllMeetings() ([]models.Meeting, error) {
+// GetAllMeetings lists the user's meetings and the unowned ones, without their
+// transcripts, which are loaded one meeting at a time through the transcript
+// endpoint
+func (s *MeetingService) GetAllMeetings(userID *uint) ([]models.Meeting, error) {
 	var meetings []models.Meeting
-	err := s.DB.Find(&meetings).Error
+	query := s.visible().Where("user_id IS NULL")
+	if userID != nil {
+		query = s.visible().Where("user_id IS NULL OR user_id = ?", *userID)
+	}
+	err := query.Omit("transcript").Find(&meetings).Error
 	if err != nil {
 		return nil, err
 	}
+
+	pointers := make([]*models.Meeting, len(meetings))
+	for i := range meetings {
+		pointers[i] = &meetings[i]
+	}
+	if err := s.fillRecordingTiers(pointers...); err != nil {
+		return nil, err
+	}
+	if err := s.fillHasTranscript(pointers...); err != nil {
+		return nil, err
+	}
 	return meetings, nil
 }
 
-func (s *MeetingService) UpdateMeeting(id uint, updates map[string]interface{}) (*models.Meeting, error) {
+// fillHasTranscript sets HasTranscript for meetings loaded without the transcript column
+func (s *MeetingService) fillHasTranscript(meetings ...*models.Meeting) error {
+	var inlineIDs []uint
+	for _, meeting := range meetings {
+		if meeting.TranscriptKey == nil {
+			inlineIDs = append(inlineIDs, meeting.ID)
+		}
+	}
+
+	withTranscript := make(map[uint]bool)
+	if len(inlineIDs) > 0 {
+		var ids []uint
+		err := s.DB.Model(&models.Meeting{}).
+			Where("id IN ? AND transcript IS NOT NULL", inlineIDs).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			withTranscript[id] = true
+		}
+	}
+	for _, meeting := range meetings {
+		meeting.HasTranscript = meeting.TranscriptKey != nil || withTranscript[meeting.ID]
+	}
+	return nil
+}
+
+// fillRecordingTiers sets RecordingTier from the files the meetings use
+func (s *MeetingService) fillRecordingTiers(meetings ...*models.Meeting) error {
+	var fileIDs []uint
+	for _, meeting := range meetings {
+		if meeting.RecordingFileID != nil {
+			fileIDs = append(fileIDs, *meeting.RecordingFileID)
+		}
+	}
+	if len(fileIDs) == 0 {
+		return nil
+	}
+
+	var files []models.File
+	if err := s.DB.Select("id", "tier").Where("id IN ?", fileIDs).Find(&files).Error; err != nil {
+		return err
+	}
+	tiers := make(map[uint]models.StorageTier, len(files))
+	for _, file := range files {
+		tiers[file.ID] = file.Tier
+	}
+	for _, meeting := range meetings {
+		if meeting.RecordingFileID != nil {
+			meeting.RecordingTier = tiers[*meeting.RecordingFileID]
+		}
+	}
+	return nil
+}
+
+// UpdateMeeting applies updates and, if the meeting is then ready, queues its
+// recording for processing in the same transaction. With reuse set, results
+// of a finished meeting with the same recording are copied instead.
+func (s *MeetingService) UpdateMeeting(id uint, updates map[string]interface{}, reuse bool) (*models.Meeting, error) {
 	var meeting models.Meeting
 
 	// 1. Find the meeting first (to ensure it exists)
-	if err := s.DB.First(&meeting, id).Error; err != nil {
+	if err := s.visible().First(&meeting, id).Error; err != nil {
 		return nil, err
 	}
+	previousStatus := meeting.Status
 
-	// 2. Apply updates using the MAP
-	// GORM will now respect empty strings if they are in the map
-	if err := s.DB.Model(&meeting).Updates(updates).Error; err != nil {
+	// 2. Prefer what the server measured on upload over client-supplied details
+	newPath, pathChanged := updates["recording_path"].(string)
+	if pathChanged {
+		pathChanged = meeting.RecordingPath == nil || *meeting.RecordingPath != newPath
+		for column, value := range s.recordingColumns(newPath) {
+			updates[column] = value
+		}
+		// A new recording starts its retention over, and any earlier failure no longer applies
+		updates["recording_expired_at"] = nil
+		updates["failure_reason"] = nil
+	}
+
+	var unused []string
+	queued := false
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		// Move the file reference along with the recording
+		if pathChanged {
+			if meeting.RecordingPath != nil {
+				release, err := releaseFile(tx, *meeting.RecordingPath)
+				if err != nil {
+					return err
+				}
+				if release {
+					unused = append(unused, *meeting.RecordingPath)
+				}
+			}
+			if err := retainFile(tx, newPath); err != nil {
+				return err
+			}
+		}
+
+		// 3. Apply updates using the MAP
+		// GORM will now respect empty strings if they are in the map
+		if err := tx.Model(&meeting).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		// 4. Queue processing through the outbox, so the meeting can't end up
+		// ready but never processed
+		if err := tx.First(&meeting, id).Error; err != nil {
+			return err
+		}
+		var err error
+		queued, err = s.processRecording(tx, &meeting, reuse)
+		if err != nil {
+			return err
+		}
+		return tx.First(&meeting, id).Error
+	})
+	if err != nil {
 		return nil, err
 	}
+	if queued {
+		s.Outbox.Notify()
+	}
+	meeting.Enqueued = &queued
+	if meeting.Status != previousStatus {
+		s.Events.PublishStatus(meeting.ID, meeting.Status, "")
+	}
+
+	// A replaced recording nobody else uses can go; the GC catches any failures
+	for _, key := range unused {
+		if err := s.Store.Delete(context.Background(), key); err != nil {
+			log.Printf("Failed to delete replaced recording %s: %v", key, err)
+		}
+	}
 
 	return &meeting, nil
 }
 
+// processRecording reuses an existing transcript when asked and one exists,
+// otherwise it queues a new meeting with a recording and moves it to "queued".
+// It reports whether a job was written to the outbox.
+func (s *MeetingService) processRecording(tx *gorm.DB, meeting *models.Meeting, reuse bool) (bool, error) {
+	if reuse {
+		// A savepoint keeps a failed copy from aborting the whole update
+		var reused bool
+		err := tx.Transaction(func(nested *gorm.DB) error {
+			var err error
+			reused, err = s.reuseResults(nested, meeting)
+			return err
+		})
+		if err != nil {
+			log.Printf("Failed to reuse transcript: %v", err)
+		}
+		if reused {
+			return false, nil
+		}
+	}
+
+	if meeting.RecordingPath == nil || meeting.Status != models.StatusCreated {
+		return false, nil
+	}
+
+	// Only the update that moves the meeting out of "created" queues it, so
+	// concurrent or repeated edits don't process the same recording twice
+	result := tx.Model(&models.Meeting{}).
+		Where("id = ? AND status = ?", meeting.ID, models.StatusCreated).
+		Update("status", models.StatusQueued)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	return true, writeMeetingJob(tx, meeting)
+}
+
+// reuseResults copies the transcript and summary from a finished meeting with
+// the same recording, so identical audio isn't processed twice. It reports
+// whether anything was copied.
+func (s *MeetingService) reuseResults(tx *gorm.DB, meeting *models.Meeting) (bool, error) {
+	if meeting.RecordingPath == nil {
+		return false, nil
+	}
+
+	// Never copy another user's transcript
+	query := tx.Where("user_id IS NULL")
+	if meeting.UserID != nil {
+		query = tx.Where("user_id IS NULL OR user_id = ?", *meeting.UserID)
+	}
+
+	var source models.Meeting
+	err := query.
+		Where("transcript IS NOT NULL OR transcript_key IS NOT NULL").
+		Where("recording_path = ? AND status = ? AND id <> ?", *meeting.RecordingPath, models.StatusCompleted, meeting.ID).
+		Order("updated_at DESC").
+		First(&source).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	updates := map[string]interface{}{
+		"transcript":            source.Transcript,
+		"transcript_key":        nil,
+		"transcript_size_bytes": source.TranscriptSizeBytes,
+		"summary":               source.Summary,
+		"key_points":            source.KeyPoints,
+		"action_items":          source.ActionItems,
+		"status":                models.StatusCompleted,
+	}
+	// Each meeting owns its offloaded transcript, so it is deleted along with it
+	if source.TranscriptKey != nil {
+		key := TranscriptKey(meeting)
+		if err := s.Store.Copy(context.Background(), *source.TranscriptKey, key); err != nil {
+			return false, fmt.Errorf("failed to copy transcript: %w", err)
+		}
+		updates["transcript_key"] = key
+	}
+
+	if err := tx.Model(meeting).Updates(updates).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteMeeting removes the meeting and every object it owns in storage.
+// The row is flagged as deleting first, so if storage fails it is never
+// dropped while its objects remain; the sweeper finishes the job later.
 func (s *MeetingService) DeleteMeeting(id uint) error {
-	err := s.DB.Delete(&models.Meeting{}, id).Error
+	var meeting models.Meeting
+	if err := s.visible().First(&meeting, id).Error; err != nil {
+		return err
+	}
+
+	err := s.DB.Model(&meeting).Update("status", models.StatusDeleting).Error
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := s.purgeMeeting(ctx, &meeting); err != nil {
+		log.Printf("Deletion of meeting %d deferred to sweeper: %v", meeting.ID, err)
+		return ErrDeletionPending
+	}
+	return nil
+}
+
+// SweepPendingDeletions retries cleanup for meetings stuck in the deleting state
+func (s *MeetingService) SweepPendingDeletions(ctx context.Context) error {
+	var meetings []models.Meeting
+	err := s.DB.WithContext(ctx).Where("status = ?", models.StatusDeleting).Find(&meetings).Error
 	if err != nil {
 		return err
 	}
+
+	for i := range meetings {
+		if err := s.purgeMeeting(ctx, &meetings[i]); err != nil {
+			log.Printf("Sweeper failed to delete meeting %d: %v", meetings[i].ID, err)
+			continue
+		}
+		log.Printf("Sweeper deleted meeting %d", meetings[i].ID)
+	}
 	return nil
 }
+
+// RunDeletionSweeper calls SweepPendingDeletions every interval until ctx is done
+func (s *MeetingService) RunDeletionSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.SweepPendingDeletions(ctx); err != nil {
+				log.Printf("Deletion sweep failed: %v", err)
+			}
+		}
+	}
+}
+
+// purgeMeeting deletes the stored objects no other meeting uses, then the row.
+// Storage deletes run inside the transaction so a failure rolls back the
+// reference counts and leaves the meeting for the sweeper to retry.
+func (s *MeetingService) purgeMeeting(ctx context.Context, meeting *models.Meeting) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, key := range storageKeys(meeting) {
+			release, err := releaseFile(tx, key)
+			if err != nil {
+				return err
+			}
+			if !release {
+				continue
+			}
+			if err := s.Store.Delete(ctx, key); err != nil {
+				return fmt.Errorf("failed to delete object %s: %w", key, err)
+			}
+		}
+		return tx.Delete(&models.Meeting{}, meeting.ID).Error
+	})
+}
+
+// recordingColumns returns the meeting columns describing an uploaded file, or
+// nil if the key wasn't uploaded through the API
+func (s *MeetingService) recordingColumns(key string) map[string]interface{} {
+	var file models.File
+	if err := s.DB.Where("key = ?", key).First(&file).Error; err != nil {
+		return nil
+	}
+
+	columns := map[string]interface{}{
+		"recording_file_id":    file.ID,
+		"recording_size_bytes": file.SizeBytes,
+		"recording_mime_type":  file.MimeType,
+	}
+	if file.DurationSeconds != nil {
+		columns["recording_duration_seconds"] = int(math.Round(*file.DurationSeconds))
+	}
+	if file.SampleRate != nil {
+		columns["recording_sample_rate"] = *file.SampleRate
+	}
+	if file.Channels != nil {
+		columns["recording_channels"] = *file.Channels
+	}
+	if file.Bitrate != nil {
+		columns["recording_bitrate"] = *file.Bitrate
+	}
+	return columns
+}
+
+// storageKeys lists every object in storage that belongs to a meeting
+func storageKeys(meeting *models.Meeting) []string {
+	var keys []string
+	if meeting.RecordingPath != nil && *meeting.RecordingPath != "" {
+		keys = append(keys, *meeting.RecordingPath)
+	}
+	if meeting.TranscriptKey != nil {
+		keys = append(keys, *meeting.TranscriptKey)
+	}
+	return keys
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errStillReferenced means a meeting picked the object up after it was listed
+var errStillReferenced = errors.New("object is referenced")
+
+type OrphanOptions struct {
+	// Objects modified more recently than this are left alone, so uploads
+	// that haven't been attached to a meeting yet aren't collected
+	GracePeriod time.Duration
+	// Only report orphans, don't delete anything
+	DryRun bool
+	// Limit the scan to keys under this prefix
+	Prefix string
+}
+
+type OrphanObject struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	Deleted      bool      `json:"deleted"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// OrphanReport summarises one reconciliation run
+type OrphanReport struct {
+	DryRun      bool           `json:"dry_run"`
+	GracePeriod string         `json:"grace_period"`
+	StartedAt   time.Time      `json:"started_at"`
+	FinishedAt  time.Time      `json:"finished_at"`
+	Scanned     int            `json:"scanned"`
+	Referenced  int            `json:"referenced"`
+	Recent      int            `json:"recent"` // Unreferenced but inside the grace period
+	Orphans     []OrphanObject `json:"orphans"`
+	OrphanBytes int64          `json:"orphan_bytes"`
+	Deleted     int            `json:"deleted"`
+	Failed      int            `json:"failed"`
+}
+
+// OrphanCollector finds objects in storage that no meeting references, such as
+// abandoned uploads and recordings of meetings deleted before cascade deletion
+type OrphanCollector struct {
+	DB    *gorm.DB
+	Store storage.Provider
+}
+
+func NewOrphanCollector(db *gorm.DB, store storage.Provider) *OrphanCollector {
+	return &OrphanCollector{DB: db, Store: store}
+}
+
+// Run compares the bucket against the keys meetings point at and the files table,
+// and deletes orphans past the grace period unless DryRun is set
+func (c *OrphanCollector) Run(ctx context.Context, opts OrphanOptions) (*OrphanReport, error) {
+	report := &OrphanReport{
+		DryRun:      opts.DryRun,
+		GracePeriod: opts.GracePeriod.String(),
+		StartedAt:   time.Now(),
+		Orphans:     []OrphanObject{},
+	}
+
+	referenced, err := c.referencedKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := report.StartedAt.Add(-opts.GracePeriod)
+	err = c.Store.List(ctx, opts.Prefix, func(object storage.ObjectInfo) error {
+		report.Scanned++
+		switch {
+		case referenced[object.Key]:
+			report.Referenced++
+		case object.LastModified.After(cutoff):
+			report.Recent++
+		default:
+			report.Orphans = append(report.Orphans, OrphanObject{
+				Key:          object.Key,
+				Size:         object.Size,
+				LastModified: object.LastModified,
+			})
+			report.OrphanBytes += object.Size
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.DryRun {
+		for i := range report.Orphans {
+			orphan := &report.Orphans[i]
+			err := c.deleteOrphan(ctx, orphan.Key)
+			switch {
+			case err == nil:
+				orphan.Deleted = true
+				report.Deleted++
+			case errors.Is(err, errStillReferenced):
+				orphan.Error = err.Error()
+			default:
+				log.Printf("Failed to delete orphaned object %s: %v", orphan.Key, err)
+				orphan.Error = err.Error()
+				report.Failed++
+			}
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// referencedKeys loads every key a meeting points at, including meetings that
+// are still being deleted, plus uploads that are in use
+func (c *OrphanCollector) referencedKeys(ctx context.Context) (map[string]bool, error) {
+	var meetingKeys []string
+	err := c.DB.WithContext(ctx).Model(&models.Meeting{}).
+		Where("recording_path IS NOT NULL AND recording_path <> ''").
+		Distinct().
+		Pluck("recording_path", &meetingKeys).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load meeting recordings: %w", err)
+	}
+
+	var transcriptKeys []string
+	err = c.DB.WithContext(ctx).Model(&models.Meeting{}).
+		Where("transcript_key IS NOT NULL").
+		Pluck("transcript_key", &transcriptKeys).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load meeting transcripts: %w", err)
+	}
+
+	var fileKeys []string
+	err = c.DB.WithContext(ctx).Model(&models.File{}).
+		Where("ref_count > 0").
+		Pluck("key", &fileKeys).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load files: %w", err)
+	}
+
+	referenced := make(map[string]bool, len(meetingKeys)+len(transcriptKeys)+len(fileKeys))
+	for _, key := range meetingKeys {
+		referenced[key] = true
+	}
+	for _, key := range transcriptKeys {
+		referenced[key] = true
+	}
+	for _, key := range fileKeys {
+		referenced[key] = true
+	}
+	return referenced, nil
+}
+
+// deleteOrphan checks the key is still unused before removing the object and its
+// file row. The storage delete runs inside the transaction, so a failure keeps the row.
+func (c *OrphanCollector) deleteOrphan(ctx context.Context, key string) error {
+	return c.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var file models.File
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&file).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && file.RefCount > 0 {
+			return errStillReferenced
+		}
+
+		var count int64
+		if err := tx.Model(&models.Meeting{}).Where("recording_path = ? OR transcript_key = ?", key, key).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errStillReferenced
+		}
+
+		if file.ID != 0 {
+			if err := tx.Delete(&file).Error; err != nil {
+				return err
+			}
+			if err := deleteFileGrants(tx, file.ID); err != nil {
+				return err
+			}
+			if err := recomputeUsage(tx, usageOwner(file.UploaderID)); err != nil {
+				return err
+			}
+		}
+		return c.Store.Delete(ctx, key)
+	})
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	outboxBatchSize  = 100
+	outboxMaxBackoff = 5 * time.Minute
+	// Dispatched messages are kept this long before they are cleaned up
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// writeMeetingJob records that the meeting must be processed. Must run inside
+// the transaction that makes the meeting ready, so the job can't be lost if
+// the queue is down or the server stops before publishing it.
+func writeMeetingJob(tx *gorm.DB, meeting *models.Meeting) error {
+	payload, err := json.Marshal(NewMeetingJob(meeting.ID, *meeting.RecordingPath, meeting.UserID))
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+	return tx.Create(&models.OutboxMessage{
+		Topic:       models.TopicMeetingJob,
+		Payload:     payload,
+		AvailableAt: time.Now(),
+	}).Error
+}
+
+// OutboxRelay publishes outbox messages to the queue. Delivery is at least
+// once: a message is marked dispatched only after the queue accepted it.
+type OutboxRelay struct {
+	DB    *gorm.DB
+	Queue Queue
+	// How often to look for messages nobody woke the relay for, or that are due a retry
+	Interval time.Duration
+
+	wake chan struct{}
+}
+
+func NewOutboxRelay(db *gorm.DB, queue Queue, interval time.Duration) *OutboxRelay {
+	return &OutboxRelay{DB: db, Queue: queue, Interval: interval, wake: make(chan struct{}, 1)}
+}
+
+// Notify wakes the relay after a transaction that wrote to the outbox has committed
+func (r *OutboxRelay) Notify() {
+	if r == nil {
+		return
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Dispatch publishes every message that is due, and returns how many went out.
+// It stops at the first failure, which usually means the queue is down.
+func (r *OutboxRelay) Dispatch(ctx context.Context) (int, error) {
+	dispatched := 0
+	for {
+		var batch []models.OutboxMessage
+		var publishErr error
+		err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// SKIP LOCKED lets several servers run the relay side by side
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("dispatched_at IS NULL AND available_at <= ?", time.Now()).
+				Order("id").
+				Limit(outboxBatchSize).
+				Find(&batch).Error
+			if err != nil {
+				return err
+			}
+
+			for i := range batch {
+				message := &batch[i]
+				if publishErr = r.publish(ctx, tx, message); publishErr != nil {
+					return r.retryLater(tx, message, publishErr)
+				}
+				if err := tx.Model(message).Update("dispatched_at", time.Now()).Error; err != nil {
+					return err
+				}
+				dispatched++
+			}
+			return nil
+		})
+		if err != nil {
+			return dispatched, err
+		}
+		if publishErr != nil {
+			return dispatched, publishErr
+		}
+		if len(batch) < outboxBatchSize {
+			return dispatched, nil
+		}
+	}
+}
+
+// publish sends one message on. tx is the transaction that marks it dispatched.
+func (r *OutboxRelay) publish(ctx context.Context, tx *gorm.DB, message *models.OutboxMessage) error {
+	switch message.Topic {
+	case models.TopicMeetingJob:
+		var job MeetingJob
+		if err := json.Unmarshal(message.Payload, &job); err != nil {
+			return fmt.Errorf("invalid job payload: %w", err)
+		}
+		pushed, err := r.Queue.Enqueue(ctx, job)
+		if err != nil {
+			return err
+		}
+		if !pushed {
+			// Already queued, e.g. a message published before a crash is sent again
+			log.Printf("Skipped duplicate job %s", job.JobID)
+			return nil
+		}
+		return recordQueued(tx, job)
+	default:
+		return fmt.Errorf("unknown outbox topic %q", message.Topic)
+	}
+}
+
+// retryLater backs a message off exponentially after a failed publish
+func (r *OutboxRelay) retryLater(tx *gorm.DB, message *models.OutboxMessage, cause error) error {
+	attempts := message.Attempts + 1
+	backoff := outboxMaxBackoff
+	if attempts < 10 {
+		backoff = min(time.Duration(1<<attempts)*time.Second, outboxMaxBackoff)
+	}
+	return tx.Model(message).Updates(map[string]interface{}{
+		"attempts":     attempts,
+		"last_error":   cause.Error(),
+		"available_at": time.Now().Add(backoff),
+	}).Error
+}
+
+// Run dispatches messages whenever it is notified and every Interval, until
+// ctx is done
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		case <-ticker.C:
+			err := r.DB.WithContext(ctx).
+				Where("dispatched_at < ?", time.Now().Add(-outboxRetention)).
+				Delete(&models.OutboxMessage{}).Error
+			if err != nil {
+				log.Printf("Failed to clean up the outbox: %v", err)
+			}
+		}
+
+		if _, err := r.Dispatch(ctx); err != nil {
+			log.Printf("Outbox relay failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/config"
+	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
+	"gorm.io/gorm"
+)
+
+const defaultJobMaxAttempts = 3
+
+// PipelineVersion is part of every job's identity. Bump it when processing
+// changes in a way that makes reprocessing the same recording worthwhile.
+const PipelineVersion = 1
+
+type MeetingJob struct {
+	// Identifies the job across deliveries: meeting, recording and pipeline version
+	JobID           string `json:"job_id"`
+	PipelineVersion int    `json:"pipeline_version"`
+
+	ID       uint   `json:"id"`
+	FilePath string `json:"file_path"`
+	// Set when the recording is in a customer's bucket, which the worker can't
+	// read directly; it must stream it from the backend instead
+	StorageProfileID uint `json:"storage_profile_id,omitempty"`
+	// Sent back as X-User-ID when the worker streams the recording of a user's meeting
+	UserID *uint `json:"user_id,omitempty"`
+	// Failed deliveries so far; the job fails for good once it reaches MaxAttempts
+	Attempts    int `json:"attempts"`
+	MaxAttempts int `json:"max_attempts"`
+}
+
+// NewMeetingJob builds the job that processes a meeting's recording
+func NewMeetingJob(meetingID uint, filePath string, userID *uint) MeetingJob {
+	job := MeetingJob{
+		JobID:           fmt.Sprintf("meeting:%d:%s:v%d", meetingID, filePath, PipelineVersion),
+		PipelineVersion: PipelineVersion,
+		ID:              meetingID,
+		FilePath:        filePath,
+		UserID:          userID,
+	}
+	if profileID, _, ok := storage.ParseProfileKey(filePath); ok {
+		job.StorageProfileID = profileID
+	}
+	return job
+}
+
+// Delivery is a job handed to a consumer. It stays with that consumer until
+// it is acked or nacked, or the consumer stops sending heartbeats.
+type Delivery struct {
+	Job      MeetingJob
+	Consumer string
+	Receipt  string // Identifies the delivery to the backend
+}
+
+// Queue holds meeting jobs until a worker has processed them. Delivery is at
+// least once: a job whose consumer dies is handed out again.
+type Queue interface {
+	// Enqueue adds a job unless one with the same JobID is queued or being
+	// processed, and reports whether it was added
+	Enqueue(ctx context.Context, job MeetingJob) (bool, error)
+	// Dequeue hands the next job to consumer, waiting up to wait for one to
+	// arrive. It returns nil if none did.
+	Dequeue(ctx context.Context, consumer string, wait time.Duration) (*Delivery, error)
+	// Ack removes a job that is done, or has failed for good
+	Ack(ctx context.Context, delivery *Delivery) error
+	// Nack puts a job back on the queue with one more attempt counted. It
+	// reports false if the delivery was already acked or reaped.
+	Nack(ctx context.Context, delivery *Delivery) (bool, error)
+	// Depth is the number of jobs waiting for a consumer
+	Depth(ctx context.Context) (int64, error)
+
+	// Heartbeat tells the queue the consumer is alive and still working
+	Heartbeat(ctx context.Context, consumer string) error
+	// Stalled returns the deliveries of consumers that haven't sent a
+	// heartbeat within timeout
+	Stalled(ctx context.Context, timeout time.Duration) ([]*Delivery, error)
+}
+
+// NewQueue connects to the backend named in the config
+func NewQueue(cfg config.QueueConfig, db *gorm.DB) (Queue, error) {
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultJobMaxAttempts
+	}
+
+	switch cfg.Backend {
+	case "redis", "":
+		return NewRedisQueue(cfg.URL, maxAttempts), nil
+	case "postgres":
+		return NewPostgresQueue(db, maxAttempts), nil
+	case "memory":
+		return NewMemoryQueue(maxAttempts), nil
+	default:
+		return nil, fmt.Errorf("unknown queue backend: %s", cfg.Backend)
+	}
+}
+
+// withMaxAttempts fills in the attempts a job gets when it doesn't say
+func withMaxAttempts(job MeetingJob, maxAttempts int) MeetingJob {
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = maxAttempts
+	}
+	return job
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryQueue keeps jobs in the server's memory. Only consumers in the same
+// process can read it and jobs are lost on restart, so it is meant for
+// development and tests.
+type MemoryQueue struct {
+	MaxAttempts int
+
+	mu         sync.Mutex
+	pending    []MeetingJob
+	inFlight   map[string]*memoryDelivery
+	jobIDs     map[string]bool // Identities of the jobs queued or in flight
+	heartbeats map[string]time.Time
+	nextID     uint64
+	// Closed and replaced whenever a job is added, to wake waiting consumers
+	added chan struct{}
+}
+
+type memoryDelivery struct {
+	job      MeetingJob
+	consumer string
+}
+
+func NewMemoryQueue(maxAttempts int) *MemoryQueue {
+	return &MemoryQueue{
+		MaxAttempts: maxAttempts,
+		inFlight:    make(map[string]*memoryDelivery),
+		jobIDs:      make(map[string]bool),
+		heartbeats:  make(map[string]time.Time),
+		added:       make(chan struct{}),
+	}
+}
+
+func (q *MemoryQueue) Enqueue(ctx context.Context, job MeetingJob) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if job.JobID != "" && q.jobIDs[job.JobID] {
+		return false, nil
+	}
+	if job.JobID != "" {
+		q.jobIDs[job.JobID] = true
+	}
+	q.push(withMaxAttempts(job, q.MaxAttempts))
+	return true, nil
+}
+
+// push appends a job and wakes waiting consumers. Must hold q.mu.
+func (q *MemoryQueue) push(job MeetingJob) {
+	q.pending = append(q.pending, job)
+	close(q.added)
+	q.added = make(chan struct{})
+}
+
+func (q *MemoryQueue) Dequeue(ctx context.Context, consumer string, wait time.Duration) (*Delivery, error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		q.mu.Lock()
+		if len(q.pending) > 0 {
+			job := q.pending[0]
+			q.pending = q.pending[1:]
+
+			q.nextID++
+			receipt := strconv.FormatUint(q.nextID, 10)
+			q.inFlight[receipt] = &memoryDelivery{job: job, consumer: consumer}
+			q.heartbeats[consumer] = time.Now()
+			q.mu.Unlock()
+			return &Delivery{Job: job, Consumer: consumer, Receipt: receipt}, nil
+		}
+		added := q.added
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			return nil, nil
+		case <-added:
+		}
+	}
+}
+
+func (q *MemoryQueue) Ack(ctx context.Context, delivery *Delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	held, ok := q.inFlight[delivery.Receipt]
+	if !ok || held.consumer != delivery.Consumer {
+		return nil
+	}
+	delete(q.inFlight, delivery.Receipt)
+	delete(q.jobIDs, held.job.JobID)
+	return nil
+}
+
+func (q *MemoryQueue) Nack(ctx context.Context, delivery *Delivery) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	held, ok := q.inFlight[delivery.Receipt]
+	if !ok || held.consumer != delivery.Consumer {
+		return false, nil
+	}
+	delete(q.inFlight, delivery.Receipt)
+	job := held.job
+	job.Attempts++
+	q.push(job)
+	return true, nil
+}
+
+func (q *MemoryQueue) Depth(ctx context.Context) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return int64(len(q.pending)), nil
+}
+
+func (q *MemoryQueue) Heartbeat(ctx context.Context, consumer string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.heartbeats[consumer] = time.Now()
+	return nil
+}
+
+func (q *MemoryQueue) Stalled(ctx context.Context, timeout time.Duration) ([]*Delivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	cutoff := time.Now().Add(-timeout)
+	var stalled []*Delivery
+	for receipt, held := range q.inFlight {
+		if q.heartbeats[held.consumer].Before(cutoff) {
+			stalled = append(stalled, &Delivery{Job: held.job, Consumer: held.consumer, Receipt: receipt})
+		}
+	}
+	for consumer, heartbeat := range q.heartbeats {
+		if heartbeat.Before(cutoff) {
+			delete(q.heartbeats, consumer)
+		}
+	}
+	return stalled, nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryQueueEnqueueDeduplicates(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(3)
+	job := NewMeetingJob(1, "recording.mp3", nil)
+
+	if added, err := q.Enqueue(ctx, job); err != nil || !added {
+		t.Fatalf("first Enqueue = %v, %v; want added", added, err)
+	}
+	if added, err := q.Enqueue(ctx, job); err != nil || added {
+		t.Fatalf("second Enqueue = %v, %v; want deduplicated", added, err)
+	}
+	if added, _ := q.Enqueue(ctx, NewMeetingJob(2, "recording.mp3", nil)); !added {
+		t.Fatal("a different meeting was deduplicated")
+	}
+	if depth, _ := q.Depth(ctx); depth != 2 {
+		t.Fatalf("Depth = %d, want 2", depth)
+	}
+
+	// Still deduplicated while in flight
+	delivery, err := q.Dequeue(ctx, "worker-1", time.Second)
+	if err != nil || delivery == nil {
+		t.Fatalf("Dequeue = %v, %v", delivery, err)
+	}
+	if added, _ := q.Enqueue(ctx, job); added {
+		t.Fatal("a job in flight was queued again")
+	}
+
+	// Released once acked
+	if err := q.Ack(ctx, delivery); err != nil {
+		t.Fatal(err)
+	}
+	if added, _ := q.Enqueue(ctx, job); !added {
+		t.Fatal("an acked job could not be queued again")
+	}
+}
+
+func TestMemoryQueueDequeue(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(3)
+
+	delivery, err := q.Dequeue(ctx, "worker-1", 10*time.Millisecond)
+	if err != nil || delivery != nil {
+		t.Fatalf("Dequeue on an empty queue = %v, %v; want nil after waiting", delivery, err)
+	}
+
+	// A waiting consumer is woken by the next job
+	done := make(chan *Delivery)
+	go func() {
+		delivery, _ := q.Dequeue(ctx, "worker-1", 5*time.Second)
+		done <- delivery
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.Enqueue(ctx, NewMeetingJob(1, "a.mp3", nil))
+
+	select {
+	case delivery := <-done:
+		if delivery == nil || delivery.Job.ID != 1 || delivery.Consumer != "worker-1" {
+			t.Fatalf("got delivery %+v", delivery)
+		}
+		if delivery.Job.MaxAttempts != 3 {
+			t.Fatalf("MaxAttempts = %d, want the queue default", delivery.Job.MaxAttempts)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiting consumer was not woken")
+	}
+
+	// Jobs come out in order
+	q.Enqueue(ctx, NewMeetingJob(2, "b.mp3", nil))
+	q.Enqueue(ctx, NewMeetingJob(3, "c.mp3", nil))
+	for _, want := range []uint{2, 3} {
+		delivery, _ := q.Dequeue(ctx, "worker-1", time.Second)
+		if delivery == nil || delivery.Job.ID != want {
+			t.Fatalf("got delivery %+v, want meeting %d", delivery, want)
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := q.Dequeue(cancelled, "worker-1", time.Second); err != context.Canceled {
+		t.Fatalf("Dequeue with a cancelled context returned %v", err)
+	}
+}
+
+func TestMemoryQueueNack(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(3)
+	job := NewMeetingJob(1, "a.mp3", nil)
+	q.Enqueue(ctx, job)
+
+	delivery, _ := q.Dequeue(ctx, "worker-1", time.Second)
+	if requeued, err := q.Nack(ctx, delivery); err != nil || !requeued {
+		t.Fatalf("Nack = %v, %v; want requeued", requeued, err)
+	}
+	if requeued, _ := q.Nack(ctx, delivery); requeued {
+		t.Fatal("a delivery was nacked twice")
+	}
+
+	retry, _ := q.Dequeue(ctx, "worker-2", time.Second)
+	if retry == nil || retry.Job.JobID != job.JobID || retry.Job.Attempts != 1 {
+		t.Fatalf("got retry %+v, want the job with one attempt", retry)
+	}
+	if retry.Receipt == delivery.Receipt {
+		t.Fatal("retry reused the old receipt")
+	}
+
+	// Still deduplicated after the retry
+	if added, _ := q.Enqueue(ctx, job); added {
+		t.Fatal("a retried job was queued again")
+	}
+}
+
+func TestMemoryQueueAckIgnoresOtherConsumers(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(3)
+	q.Enqueue(ctx, NewMeetingJob(1, "a.mp3", nil))
+
+	delivery, _ := q.Dequeue(ctx, "worker-1", time.Second)
+	stolen := *delivery
+	stolen.Consumer = "worker-2"
+	if err := q.Ack(ctx, &stolen); err != nil {
+		t.Fatal(err)
+	}
+	if requeued, _ := q.Nack(ctx, delivery); !requeued {
+		t.Fatal("another consumer's ack removed the delivery")
+	}
+}
+
+func TestMemoryQueueStalled(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(3)
+	q.Enqueue(ctx, NewMeetingJob(1, "a.mp3", nil))
+	q.Enqueue(ctx, NewMeetingJob(2, "b.mp3", nil))
+
+	dead, _ := q.Dequeue(ctx, "dead-worker", time.Second)
+	live, _ := q.Dequeue(ctx, "live-worker", time.Second)
+
+	time.Sleep(30 * time.Millisecond)
+	q.Heartbeat(ctx, "live-worker")
+
+	stalled, err := q.Stalled(ctx, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stalled) != 1 || stalled[0].Receipt != dead.Receipt || stalled[0].Consumer != "dead-worker" {
+		t.Fatalf("Stalled = %+v, want only the dead worker's delivery", stalled)
+	}
+
+	// The reaper puts it back through the normal Nack path
+	if requeued, _ := q.Nack(ctx, stalled[0]); !requeued {
+		t.Fatal("stalled delivery could not be requeued")
+	}
+	if stalled, _ := q.Stalled(ctx, 20*time.Millisecond); len(stalled) != 0 {
+		t.Fatalf("Stalled after requeue = %+v", stalled)
+	}
+	if err := q.Ack(ctx, live); err != nil {
+		t.Fatal(err)
+	}
+	if depth, _ := q.Depth(ctx); depth != 1 {
+		t.Fatalf("Depth = %d, want the requeued job", depth)
+	}
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// How often Dequeue checks for new jobs while it waits
+const postgresQueuePollInterval = 500 * time.Millisecond
+
+// PostgresQueue keeps jobs in the queue_jobs table, for deployments without
+// Redis. Workers claim rows with FOR UPDATE SKIP LOCKED, so they never block
+// on each other, and a delivery's receipt is the row ID.
+type PostgresQueue struct {
+	DB          *gorm.DB
+	MaxAttempts int
+}
+
+func NewPostgresQueue(db *gorm.DB, maxAttempts int) *PostgresQueue {
+	return &PostgresQueue{DB: db, MaxAttempts: maxAttempts}
+}
+
+func (q *PostgresQueue) Enqueue(ctx context.Context, job MeetingJob) (bool, error) {
+	job = withMaxAttempts(job, q.MaxAttempts)
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal job: %v", err)
+	}
+
+	result := q.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "job_id"}}, DoNothing: true}).
+		Create(&models.QueueJob{JobID: job.JobID, Payload: payload, Attempts: job.Attempts})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to enqueue job: %v", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (q *PostgresQueue) Dequeue(ctx context.Context, consumer string, wait time.Duration) (*Delivery, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		delivery, err := q.claim(ctx, consumer)
+		if err != nil || delivery != nil {
+			return delivery, err
+		}
+		if !time.Now().Before(deadline) {
+			return nil, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(min(postgresQueuePollInterval, time.Until(deadline))):
+		}
+	}
+}
+
+// claim hands the oldest unclaimed job to consumer, if there is one
+func (q *PostgresQueue) claim(ctx context.Context, consumer string) (*Delivery, error) {
+	var row models.QueueJob
+	err := q.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("consumer IS NULL").
+			Order("id").
+			Take(&row).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&row).Updates(map[string]interface{}{
+			"consumer":     consumer,
+			"heartbeat_at": time.Now(),
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to dequeue job: %v", err)
+	}
+	return rowDelivery(&row, consumer)
+}
+
+func (q *PostgresQueue) Ack(ctx context.Context, delivery *Delivery) error {
+	return q.DB.WithContext(ctx).
+		Where("id = ? AND consumer = ?", delivery.Receipt, delivery.Consumer).
+		Delete(&models.QueueJob{}).Error
+}
+
+func (q *PostgresQueue) Nack(ctx context.Context, delivery *Delivery) (bool, error) {
+	result := q.DB.WithContext(ctx).Model(&models.QueueJob{}).
+		Where("id = ? AND consumer = ?", delivery.Receipt, delivery.Consumer).
+		Updates(map[string]interface{}{
+			"attempts":     gorm.Expr("attempts + 1"),
+			"consumer":     nil,
+			"heartbeat_at": nil,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to requeue job: %v", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (q *PostgresQueue) Depth(ctx context.Context) (int64, error) {
+	var depth int64
+	err := q.DB.WithContext(ctx).Model(&models.QueueJob{}).Where("consumer IS NULL").Count(&depth).Error
+	return depth, err
+}
+
+func (q *PostgresQueue) Heartbeat(ctx context.Context, consumer string) error {
+	return q.DB.WithContext(ctx).Model(&models.QueueJob{}).
+		Where("consumer = ?", consumer).
+		Update("heartbeat_at", time.Now()).Error
+}
+
+func (q *PostgresQueue) Stalled(ctx context.Context, timeout time.Duration) ([]*Delivery, error) {
+	var rows []models.QueueJob
+	err := q.DB.WithContext(ctx).
+		Where("consumer IS NOT NULL AND heartbeat_at < ?", time.Now().Add(-timeout)).
+		Order("id").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stalled := make([]*Delivery, 0, len(rows))
+	for i := range rows {
+		delivery, err := rowDelivery(&rows[i], *rows[i].Consumer)
+		if err != nil {
+			log.Printf("Skipping stalled job: %v", err)
+			continue
+		}
+		stalled = append(stalled, delivery)
+	}
+	return stalled, nil
+}
+
+// rowDelivery turns a claimed row into a delivery, taking the attempts from
+// their column
+func rowDelivery(row *models.QueueJob, consumer string) (*Delivery, error) {
+	var job MeetingJob
+	if err := json.Unmarshal(row.Payload, &job); err != nil {
+		return nil, fmt.Errorf("invalid payload in queue job %d: %v", row.ID, err)
+	}
+	job.Attempts = row.Attempts
+	return &Delivery{Job: job, Consumer: consumer, Receipt: strconv.FormatUint(uint64(row.ID), 10)}, nil
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Workers LMOVE jobs from the queue onto their own processing list and remove
+// them once done, so a job is never only held in a worker's memory. Each worker
+// also writes a heartbeat; the reaper puts back the jobs of workers that stop.
+// The key names match the Python worker.
+const (
+	queueKey            = "meeting_jobs"
+	processingKeyPrefix = "meeting_jobs:processing:"
+	workerHeartbeatsKey = "meeting_jobs:heartbeats"
+)
+
+// A job's identity is held under dedupKeyPrefix while it is queued or running,
+// so the same job is never queued twice. The worker removes it when the job
+// finishes or fails for good; the TTL covers workers that never get there.
+const (
+	dedupKeyPrefix = "meeting_jobs:dedup:"
+	jobDedupTTL    = 24 * time.Hour
+)
+
+// enqueueScript pushes a job unless one with the same identity is pending
+var enqueueScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], '1', 'NX', 'EX', ARGV[1]) then
+	redis.call('RPUSH', KEYS[2], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// requeueScript moves a job from a processing list back onto the queue with its
+// new payload, unless the worker acknowledged it in the meantime
+var requeueScript = redis.NewScript(`
+if redis.call('LREM', KEYS[1], 1, ARGV[1]) == 1 then
+	redis.call('RPUSH', KEYS[2], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// RedisQueue keeps jobs in Redis lists. A delivery's receipt is the raw
+// payload, which is how it is found on the consumer's processing list.
+type RedisQueue struct {
+	Client      *redis.Client
+	MaxAttempts int
+}
+
+func NewRedisQueue(addr string, maxAttempts int) *RedisQueue {
+	client := redis.NewClient(&redis.Options{
+		Addr: addr, // e.g., "localhost:6379"
+	})
+	return &RedisQueue{Client: client, MaxAttempts: maxAttempts}
+}
+
+func (q *RedisQueue) Enqueue(ctx context.Context, job MeetingJob) (bool, error) {
+	jobJSON, err := json.Marshal(withMaxAttempts(job, q.MaxAttempts))
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal job: %v", err)
+	}
+
+	// Push to Redis List (RPUSH appends to the tail)
+	// "meeting_jobs" matches the QUEUE_NAME in your Python script
+	keys := []string{dedupKeyPrefix + job.JobID, queueKey}
+	pushed, err := enqueueScript.Run(ctx, q.Client, keys, int(jobDedupTTL.Seconds()), jobJSON).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue job: %v", err)
+	}
+	return pushed == 1, nil
+}
+
+func (q *RedisQueue) Dequeue(ctx context.Context, consumer string, wait time.Duration) (*Delivery, error) {
+	raw, err := q.Client.BLMove(ctx, queueKey, processingKeyPrefix+consumer, "LEFT", "RIGHT", wait).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to dequeue job: %v", err)
+	}
+
+	var job MeetingJob
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		q.Client.LRem(ctx, processingKeyPrefix+consumer, 1, raw)
+		return nil, fmt.Errorf("dropped unreadable job: %v", err)
+	}
+	return &Delivery{Job: job, Consumer: consumer, Receipt: raw}, nil
+}
+
+// Ack drops the job from the consumer's processing list and releases its
+// identity, so the same job can be queued again later
+func (q *RedisQueue) Ack(ctx context.Context, delivery *Delivery) error {
+	_, err := q.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, processingKeyPrefix+delivery.Consumer, 1, delivery.Receipt)
+		if delivery.Job.JobID != "" {
+			pipe.Del(ctx, dedupKeyPrefix+delivery.Job.JobID)
+		}
+		return nil
+	})
+	return err
+}
+
+func (q *RedisQueue) Nack(ctx context.Context, delivery *Delivery) (bool, error) {
+	job := delivery.Job
+	job.Attempts++
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal job: %v", err)
+	}
+
+	keys := []string{processingKeyPrefix + delivery.Consumer, queueKey}
+	moved, err := requeueScript.Run(ctx, q.Client, keys, delivery.Receipt, payload).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue job: %v", err)
+	}
+	return moved == 1, nil
+}
+
+func (q *RedisQueue) Depth(ctx context.Context) (int64, error) {
+	return q.Client.LLen(ctx, queueKey).Result()
+}
+
+func (q *RedisQueue) Heartbeat(ctx context.Context, consumer string) error {
+	now := float64(time.Now().UnixNano()) / float64(time.Second)
+	return q.Client.HSet(ctx, workerHeartbeatsKey, consumer, now).Err()
+}
+
+// Stalled returns the jobs on the processing lists of workers whose heartbeat
+// is missing or too old. Heartbeats of workers that left nothing behind are
+// dropped.
+func (q *RedisQueue) Stalled(ctx context.Context, timeout time.Duration) ([]*Delivery, error) {
+	heartbeats, err := q.workerHeartbeats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-timeout)
+
+	var stalled []*Delivery
+	iter := q.Client.Scan(ctx, 0, processingKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		consumer := key[len(processingKeyPrefix):]
+		if heartbeat, ok := heartbeats[consumer]; ok && heartbeat.After(cutoff) {
+			continue
+		}
+
+		jobs, err := q.Client.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range jobs {
+			var job MeetingJob
+			if err := json.Unmarshal([]byte(raw), &job); err != nil {
+				log.Printf("Dropping unreadable job held by worker %s: %v", consumer, err)
+				q.Client.LRem(ctx, key, 1, raw)
+				continue
+			}
+			stalled = append(stalled, &Delivery{Job: job, Consumer: consumer, Receipt: raw})
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	for consumer, heartbeat := range heartbeats {
+		if heartbeat.Before(cutoff) {
+			if err := q.Client.HDel(ctx, workerHeartbeatsKey, consumer).Err(); err != nil {
+				log.Printf("Failed to forget worker %s: %v", consumer, err)
+			}
+		}
+	}
+	return stalled, nil
+}
+
+// workerHeartbeats returns when each worker last reported in
+func (q *RedisQueue) workerHeartbeats(ctx context.Context) (map[string]time.Time, error) {
+	raw, err := q.Client.HGetAll(ctx, workerHeartbeatsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	heartbeats := make(map[string]time.Time, len(raw))
+	for consumer, value := range raw {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		heartbeats[consumer] = time.Unix(0, int64(seconds*float64(time.Second)))
+	}
+	return heartbeats, nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// QuotaError reports how far an upload would go over the owner's quota
+type QuotaError struct {
+	UsedBytes      int64
+	QuotaBytes     int64
+	RequestedBytes int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("storage quota exceeded: %d of %d bytes used, %d more requested", e.UsedBytes, e.QuotaBytes, e.RequestedBytes)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// Usage is an owner's consumption and limit
+type Usage struct {
+	OwnerID        uint   `json:"owner_id"`
+	BytesUsed      int64  `json:"bytes_used"`
+	FileCount      int64  `json:"file_count"`
+	QuotaBytes     int64  `json:"quota_bytes"`     // 0 means unlimited
+	RemainingBytes *int64 `json:"remaining_bytes"` // nil when unlimited
+}
+
+// QuotaService limits how much each owner stores in the default bucket.
+// Files in a storage profile with its own bucket don't count.
+type QuotaService struct {
+	DB           *gorm.DB
+	DefaultBytes int64
+}
+
+func NewQuotaService(db *gorm.DB, defaultBytes int64) *QuotaService {
+	return &QuotaService{DB: db, DefaultBytes: defaultBytes}
+}
+
+// usageOwner maps an uploader to its usage row; anonymous uploads share owner 0
+func usageOwner(uploaderID *uint) uint {
+	if uploaderID == nil {
+		return 0
+	}
+	return *uploaderID
+}
+
+// Usage returns the owner's usage and quota
+func (s *QuotaService) Usage(userID *uint) (*Usage, error) {
+	var row models.StorageUsage
+	err := s.DB.Where("owner_id = ?", usageOwner(userID)).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Owners with files from before usage was tracked are totalled on first use
+		if err := recomputeUsage(s.DB, usageOwner(userID)); err != nil {
+			return nil, err
+		}
+		err = s.DB.Where("owner_id = ?", usageOwner(userID)).First(&row).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &Usage{
+		OwnerID:    usageOwner(userID),
+		BytesUsed:  row.BytesUsed,
+		FileCount:  row.FileCount,
+		QuotaBytes: s.DefaultBytes,
+	}
+	if row.QuotaBytes != nil {
+		usage.QuotaBytes = *row.QuotaBytes
+	}
+	if usage.QuotaBytes > 0 {
+		remaining := max(usage.QuotaBytes-usage.BytesUsed, 0)
+		usage.RemainingBytes = &remaining
+	}
+	return usage, nil
+}
+
+// Remaining returns how many more bytes the owner may store in profileID, and
+// false if there is no limit
+func (s *QuotaService) Remaining(userID *uint, profileID uint) (int64, bool, error) {
+	if profileID != 0 {
+		ownBucket, err := s.profileHasBucket(profileID)
+		if err != nil || ownBucket {
+			return 0, false, err
+		}
+	}
+
+	usage, err := s.Usage(userID)
+	if err != nil {
+		return 0, false, err
+	}
+	if usage.RemainingBytes == nil {
+		return 0, false, nil
+	}
+	return *usage.RemainingBytes, true, nil
+}
+
+// Check fails with a *QuotaError if size more bytes don't fit in the quota
+func (s *QuotaService) Check(userID *uint, profileID uint, size int64) error {
+	remaining, limited, err := s.Remaining(userID, profileID)
+	if err != nil || !limited || size <= remaining {
+		return err
+	}
+	return s.Exceeded(userID, size)
+}
+
+// Exceeded builds the *QuotaError for an upload of size bytes that didn't fit
+func (s *QuotaService) Exceeded(userID *uint, size int64) error {
+	usage, err := s.Usage(userID)
+	if err != nil {
+		return err
+	}
+	return &QuotaError{UsedBytes: usage.BytesUsed, QuotaBytes: usage.QuotaBytes, RequestedBytes: size}
+}
+
+// SetQuota overrides the default quota for an owner; nil restores the default
+func (s *QuotaService) SetQuota(ownerID uint, quotaBytes *int64) (*Usage, error) {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := recomputeUsage(tx, ownerID); err != nil {
+			return err
+		}
+		return tx.Model(&models.StorageUsage{}).
+			Where("owner_id = ?", ownerID).
+			Update("quota_bytes", quotaBytes).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Usage(&ownerID)
+}
+
+func (s *QuotaService) profileHasBucket(profileID uint) (bool, error) {
+	var profile models.StorageProfile
+	if err := s.DB.Select("bucket").First(&profile, profileID).Error; err != nil {
+		return false, err
+	}
+	return profile.Bucket != "", nil
+}
+
+// recomputeUsage totals the owner's files in the default bucket, including
+// profiles that only use a prefix of it, and stores the result
+func recomputeUsage(tx *gorm.DB, ownerID uint) error {
+	owner := "uploader_id = ?"
+	args := []interface{}{ownerID}
+	if ownerID == 0 {
+		owner = "uploader_id IS NULL"
+		args = nil
+	}
+
+	return tx.Exec(`
+		INSERT INTO storage_usages (owner_id, bytes_used, file_count, updated_at)
+		SELECT ?, COALESCE(SUM(size_bytes), 0), COUNT(*), NOW()
+		FROM files
+		WHERE `+owner+` AND (storage_profile_id = 0 OR storage_profile_id IN (
+			SELECT id FROM storage_profiles WHERE bucket IS NULL OR bucket = ''
+		))
+		ON CONFLICT (owner_id) DO UPDATE SET
+			bytes_used = EXCLUDED.bytes_used,
+			file_count = EXCLUDED.file_count,
+			updated_at = EXCLUDED.updated_at`,
+		append([]interface{}{ownerID}, args...)...,
+	).Error
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/config"
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errNotExpired means the meeting changed after it was picked for expiry
+var errNotExpired = errors.New("artifact is no longer expired")
+
+// Meetings loaded per sweep query
+const retentionBatchSize = 100
+
+// RetentionRule is the retention that applies to one artifact of a meeting
+type RetentionRule struct {
+	Days   int    `json:"days"`   // Zero keeps the artifact forever
+	Source string `json:"source"` // "global", "workspace" or "meeting"
+}
+
+// RetentionReport summarises one sweep
+type RetentionReport struct {
+	Checked            int `json:"checked"`
+	RecordingsExpired  int `json:"recordings_expired"`
+	TranscriptsExpired int `json:"transcripts_expired"`
+	Failed             int `json:"failed"`
+}
+
+type RetentionService struct {
+	DB       *gorm.DB
+	Store    storage.Provider
+	Defaults config.RetentionConfig
+}
+
+func NewRetentionService(db *gorm.DB, store storage.Provider, defaults config.RetentionConfig) *RetentionService {
+	return &RetentionService{DB: db, Store: store, Defaults: defaults}
+}
+
+// GetPolicy returns the workspace's overrides, or nil if it has none
+func (s *RetentionService) GetPolicy(userID uint) (*models.RetentionPolicy, error) {
+	var policy models.RetentionPolicy
+	err := s.DB.Where("user_id = ?", userID).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SetPolicy replaces the workspace's overrides; nil days fall back to the global default
+func (s *RetentionService) SetPolicy(userID uint, recordingDays, transcriptDays *int) (*models.RetentionPolicy, error) {
+	policy := &models.RetentionPolicy{
+		UserID:         userID,
+		RecordingDays:  recordingDays,
+		TranscriptDays: transcriptDays,
+	}
+	err := s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"recording_days", "transcript_days", "updated_at"}),
+	}).Create(policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.GetPolicy(userID)
+}
+
+// SetMeetingRetention replaces the meeting's overrides; nil days inherit
+func (s *RetentionService) SetMeetingRetention(meeting *models.Meeting, recordingDays, transcriptDays *int) error {
+	return s.DB.Model(meeting).Updates(map[string]interface{}{
+		"recording_retention_days":  recordingDays,
+		"transcript_retention_days": transcriptDays,
+	}).Error
+}
+
+// MeetingRules resolves the retention of a meeting with its owner's policy
+func (s *RetentionService) MeetingRules(meeting *models.Meeting) (recording, transcript RetentionRule, err error) {
+	var policy *models.RetentionPolicy
+	if meeting.UserID != nil {
+		policy, err = s.GetPolicy(*meeting.UserID)
+		if err != nil {
+			return recording, transcript, err
+		}
+	}
+	recording, transcript = s.Rules(meeting, policy)
+	return recording, transcript, nil
+}
+
+// GetAudits lists what retention has removed from a meeting
+func (s *RetentionService) GetAudits(meetingID uint) ([]models.RetentionAudit, error) {
+	var audits []models.RetentionAudit
+	err := s.DB.Where("meeting_id = ?", meetingID).Order("created_at").Find(&audits).Error
+	if err != nil {
+		return nil, err
+	}
+	return audits, nil
+}
+
+// SetLegalHold stops or resumes expiry of everything the meeting holds
+func (s *RetentionService) SetLegalHold(meeting *models.Meeting, hold bool) error {
+	return s.DB.Model(meeting).Update("legal_hold", hold).Error
+}
+
+// Rules resolves the retention of a meeting's recording and transcript,
+// from the meeting's own settings, then the workspace's, then the defaults
+func (s *RetentionService) Rules(meeting *models.Meeting, policy *models.RetentionPolicy) (recording, transcript RetentionRule) {
+	recording = RetentionRule{Days: s.Defaults.RecordingDays, Source: "global"}
+	transcript = RetentionRule{Days: s.Defaults.TranscriptDays, Source: "global"}
+
+	if policy != nil && policy.RecordingDays != nil {
+		recording = RetentionRule{Days: *policy.RecordingDays, Source: "workspace"}
+	}
+	if policy != nil && policy.TranscriptDays != nil {
+		transcript = RetentionRule{Days: *policy.TranscriptDays, Source: "workspace"}
+	}
+	if meeting.RecordingRetentionDays != nil {
+		recording = RetentionRule{Days: *meeting.RecordingRetentionDays, Source: "meeting"}
+	}
+	if meeting.TranscriptRetentionDays != nil {
+		transcript = RetentionRule{Days: *meeting.TranscriptRetentionDays, Source: "meeting"}
+	}
+	return recording, transcript
+}
+
+// expiresAt is when an artifact of the meeting expires under rule, if ever
+func expiresAt(meeting *models.Meeting, rule RetentionRule) (time.Time, bool) {
+	if rule.Days <= 0 {
+		return time.Time{}, false
+	}
+	return meeting.CreatedAt.AddDate(0, 0, rule.Days), true
+}
+
+// Sweep removes every recording and transcript past its retention. Meetings
+// still being processed and meetings on legal hold are left alone.
+func (s *RetentionService) Sweep(ctx context.Context) (*RetentionReport, error) {
+	report := &RetentionReport{}
+	now := time.Now()
+
+	var meetings []models.Meeting
+	err := s.DB.WithContext(ctx).
+		Where("legal_hold = ? AND status IN ?", false, []models.MeetingStatus{models.StatusCompleted, models.StatusFailed}).
+		Where("(recording_path IS NOT NULL AND recording_path <> '') OR transcript IS NOT NULL OR transcript_key IS NOT NULL").
+		FindInBatches(&meetings, retentionBatchSize, func(tx *gorm.DB, batch int) error {
+			policies, err := s.batchPolicies(ctx, meetings)
+			if err != nil {
+				return err
+			}
+
+			for i := range meetings {
+				meeting := &meetings[i]
+				report.Checked++
+
+				var policy *models.RetentionPolicy
+				if meeting.UserID != nil {
+					policy = policies[*meeting.UserID]
+				}
+				recording, transcript := s.Rules(meeting, policy)
+
+				if meeting.RecordingPath != nil && *meeting.RecordingPath != "" {
+					if deadline, ok := expiresAt(meeting, recording); ok && now.After(deadline) {
+						s.expire(ctx, meeting, models.ArtifactRecording, recording, deadline, report)
+					}
+				}
+				if meeting.Transcript != nil || meeting.TranscriptKey != nil {
+					if deadline, ok := expiresAt(meeting, transcript); ok && now.After(deadline) {
+						s.expire(ctx, meeting, models.ArtifactTranscript, transcript, deadline, report)
+					}
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// RunSweeper calls Sweep every interval until ctx is done
+func (s *RetentionService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.Sweep(ctx)
+			if err != nil {
+				log.Printf("Retention sweep failed: %v", err)
+				continue
+			}
+			if report.RecordingsExpired > 0 || report.TranscriptsExpired > 0 || report.Failed > 0 {
+				log.Printf("Retention sweep expired %d recordings and %d transcripts, %d failed",
+					report.RecordingsExpired, report.TranscriptsExpired, report.Failed)
+			}
+		}
+	}
+}
+
+// batchPolicies loads the workspace policies of the meetings' owners
+func (s *RetentionService) batchPolicies(ctx context.Context, meetings []models.Meeting) (map[uint]*models.RetentionPolicy, error) {
+	var userIDs []uint
+	for _, meeting := range meetings {
+		if meeting.UserID != nil {
+			userIDs = append(userIDs, *meeting.UserID)
+		}
+	}
+
+	policies := make(map[uint]*models.RetentionPolicy)
+	if len(userIDs) == 0 {
+		return policies, nil
+	}
+
+	var rows []models.RetentionPolicy
+	if err := s.DB.WithContext(ctx).Where("user_id IN ?", userIDs).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load retention policies: %w", err)
+	}
+	for i := range rows {
+		policies[rows[i].UserID] = &rows[i]
+	}
+	return policies, nil
+}
+
+// expire removes one artifact and counts the outcome in report
+func (s *RetentionService) expire(ctx context.Context, meeting *models.Meeting, artifact models.RetentionArtifact, rule RetentionRule, deadline time.Time, report *RetentionReport) {
+	err := s.expireArtifact(ctx, meeting.ID, artifact, rule, deadline)
+	switch {
+	case err == nil:
+		log.Printf("Expired %s of meeting %d (%s retention of %d days)", artifact, meeting.ID, rule.Source, rule.Days)
+		if artifact == models.ArtifactRecording {
+			report.RecordingsExpired++
+		} else {
+			report.TranscriptsExpired++
+		}
+	case errors.Is(err, errNotExpired):
+	default:
+		log.Printf("Failed to expire %s of meeting %d: %v", artifact, meeting.ID, err)
+		report.Failed++
+	}
+}
+
+// expireArtifact removes the artifact, clears it from the meeting and writes
+// the audit record in one transaction. The meeting is locked and checked
+// again first, so a legal hold placed since the meeting was listed still wins.
+// As elsewhere, the storage delete runs last inside the transaction so a
+// failure leaves the meeting untouched for the next sweep.
+func (s *RetentionService) expireArtifact(ctx context.Context, meetingID uint, artifact models.RetentionArtifact, rule RetentionRule, deadline time.Time) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var meeting models.Meeting
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&meeting, meetingID).Error
+		if err != nil {
+			return err
+		}
+		if meeting.LegalHold || meeting.Status == models.StatusDeleting {
+			return errNotExpired
+		}
+
+		now := time.Now()
+		audit := &models.RetentionAudit{
+			MeetingID:     meeting.ID,
+			UserID:        meeting.UserID,
+			Artifact:      artifact,
+			PolicySource:  rule.Source,
+			RetentionDays: rule.Days,
+			ExpiredAt:     deadline,
+		}
+
+		var unused string
+		switch artifact {
+		case models.ArtifactRecording:
+			if meeting.RecordingPath == nil || *meeting.RecordingPath == "" {
+				return errNotExpired
+			}
+			key := *meeting.RecordingPath
+			release, err := releaseFile(tx, key)
+			if err != nil {
+				return err
+			}
+			if release {
+				unused = key
+			}
+			audit.ObjectKey = &key
+			audit.ObjectDeleted = release
+
+			err = tx.Model(&meeting).Updates(map[string]interface{}{
+				"recording_path":       nil,
+				"recording_file_id":    nil,
+				"recording_expired_at": now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		case models.ArtifactTranscript:
+			if meeting.Transcript == nil && meeting.TranscriptKey == nil {
+				return errNotExpired
+			}
+			if meeting.TranscriptKey != nil {
+				unused = *meeting.TranscriptKey
+				audit.ObjectKey = meeting.TranscriptKey
+				audit.ObjectDeleted = true
+			}
+			err := tx.Model(&meeting).Updates(map[string]interface{}{
+				"transcript":            nil,
+				"transcript_key":        nil,
+				"transcript_size_bytes": nil,
+				"transcript_expired_at": now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(audit).Error; err != nil {
+			return err
+		}
+		if unused != "" {
+			if err := s.Store.Delete(ctx, unused); err != nil {
+				return fmt.Errorf("failed to delete object %s: %w", unused, err)
+			}
+		}
+		return nil
+	})
+}
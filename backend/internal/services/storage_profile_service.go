@@ -0,0 +1,347 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/config"
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNoStorageProfile       = errors.New("no storage profile")
+	ErrInvalidStorageProfile  = errors.New("invalid storage profile")
+	ErrCredentialsUnavailable = errors.New("storage credentials keys are not configured")
+	ErrBucketUnreachable      = errors.New("bucket could not be reached with the given credentials")
+)
+
+// StorageProfileInput is what a user supplies to bring their own bucket
+type StorageProfileInput struct {
+	Driver    string `json:"driver"`
+	Bucket    string `json:"bucket"`
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Prefix    string `json:"prefix"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+}
+
+// StorageProfileService stores per-user storage settings and turns them into
+// providers for storage.Router
+type StorageProfileService struct {
+	DB *gorm.DB
+	// Seals bucket secrets; nil when STORAGE_CREDENTIALS_KEYS is unset, which
+	// only allows profiles that use a prefix of the default bucket
+	Keys *storage.Keyring
+	// The default store, which holds profiles without a bucket of their own
+	Default storage.Provider
+	// Encryption at rest applies to profile buckets as well
+	Storage config.StorageConfig
+}
+
+var _ storage.ProfileResolver = (*StorageProfileService)(nil)
+
+func NewStorageProfileService(db *gorm.DB, defaultStore storage.Provider, cfg config.StorageConfig) (*StorageProfileService, error) {
+	s := &StorageProfileService{DB: db, Default: defaultStore, Storage: cfg}
+	if cfg.CredentialsKeys != "" {
+		keys, err := storage.ParseKeyring(cfg.CredentialsKeys)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load storage credentials keys: %w", err)
+		}
+		s.Keys = keys
+	}
+	return s, nil
+}
+
+// ActiveProfile returns the profile new uploads of the user go to
+func (s *StorageProfileService) ActiveProfile(userID *uint) (*models.StorageProfile, error) {
+	if userID == nil {
+		return nil, ErrNoStorageProfile
+	}
+
+	var profile models.StorageProfile
+	err := s.DB.Where("user_id = ? AND retired_at IS NULL", *userID).
+		Order("id DESC").
+		First(&profile).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoStorageProfile
+	}
+	if err != nil {
+		return nil, err
+	}
+	profile.HasSecretKey = len(profile.SecretKeySealed) > 0
+	return &profile, nil
+}
+
+// UploadProfileID is the profile new uploads of the user are stored in,
+// 0 for the default store. Build keys with storage.ProfileKey.
+func (s *StorageProfileService) UploadProfileID(userID *uint) (uint, error) {
+	profile, err := s.ActiveProfile(userID)
+	if errors.Is(err, ErrNoStorageProfile) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return profile.ID, nil
+}
+
+// CreateProfile checks the bucket is reachable, then makes the new profile the
+// user's active one. Earlier profiles are retired but keep serving their objects.
+func (s *StorageProfileService) CreateProfile(ctx context.Context, userID uint, input StorageProfileInput) (*models.StorageProfile, error) {
+	profile := &models.StorageProfile{
+		UserID:    userID,
+		Driver:    strings.ToLower(strings.TrimSpace(input.Driver)),
+		Bucket:    strings.TrimSpace(input.Bucket),
+		Endpoint:  strings.TrimSpace(input.Endpoint),
+		Region:    strings.TrimSpace(input.Region),
+		Prefix:    strings.Trim(strings.TrimSpace(input.Prefix), "/"),
+		AccessKey: strings.TrimSpace(input.AccessKey),
+	}
+	if profile.Driver == "" {
+		profile.Driver = "s3"
+	}
+	if profile.Region == "" {
+		profile.Region = "us-east-1"
+	}
+
+	switch {
+	case profile.Driver != "s3" && profile.Driver != "minio":
+		return nil, fmt.Errorf("%w: driver must be s3 or minio", ErrInvalidStorageProfile)
+	case strings.Contains(profile.Prefix, ".."):
+		return nil, fmt.Errorf("%w: prefix must not contain ..", ErrInvalidStorageProfile)
+	case profile.Bucket == "" && (profile.AccessKey != "" || input.SecretKey != ""):
+		return nil, fmt.Errorf("%w: credentials need a bucket", ErrInvalidStorageProfile)
+	case profile.Bucket != "" && (profile.AccessKey == "" || input.SecretKey == ""):
+		return nil, fmt.Errorf("%w: a bucket needs an access key and secret key", ErrInvalidStorageProfile)
+	case profile.Driver == "minio" && profile.Bucket != "" && profile.Endpoint == "":
+		return nil, fmt.Errorf("%w: minio needs an endpoint", ErrInvalidStorageProfile)
+	}
+	if profile.Bucket != "" && profile.Endpoint == "" {
+		profile.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", profile.Region)
+	}
+	if profile.Bucket != "" {
+		if err := s.checkEndpoint(ctx, profile.Endpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	if profile.Bucket != "" {
+		if err := s.sealSecret(profile, input.SecretKey); err != nil {
+			return nil, err
+		}
+		if err := s.checkBucket(ctx, profile); err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.StorageProfile{}).
+			Where("user_id = ? AND retired_at IS NULL", userID).
+			Update("retired_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(profile).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	profile.HasSecretKey = len(profile.SecretKeySealed) > 0
+	return profile, nil
+}
+
+// UpdateCredentials replaces the keys of the user's active profile, e.g. after
+// the customer rotates them. The caller must forget the cached provider.
+func (s *StorageProfileService) UpdateCredentials(ctx context.Context, userID uint, accessKey, secretKey string) (*models.StorageProfile, error) {
+	profile, err := s.ActiveProfile(&userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Bucket == "" {
+		return nil, fmt.Errorf("%w: profile uses the default bucket", ErrInvalidStorageProfile)
+	}
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("%w: access key and secret key are required", ErrInvalidStorageProfile)
+	}
+
+	profile.AccessKey = accessKey
+	if err := s.sealSecret(profile, secretKey); err != nil {
+		return nil, err
+	}
+	if err := s.checkBucket(ctx, profile); err != nil {
+		return nil, err
+	}
+
+	err = s.DB.Model(profile).Updates(map[string]any{
+		"access_key":        profile.AccessKey,
+		"secret_key_id":     profile.SecretKeyID,
+		"secret_key_sealed": profile.SecretKeySealed,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// RetireProfile sends the user's new uploads back to the default store
+func (s *StorageProfileService) RetireProfile(userID uint) error {
+	result := s.DB.Model(&models.StorageProfile{}).
+		Where("user_id = ? AND retired_at IS NULL", userID).
+		Update("retired_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNoStorageProfile
+	}
+	return nil
+}
+
+// ResolveProfile builds the provider for a profile, retired or not, so every
+// object stays readable from wherever it was written
+func (s *StorageProfileService) ResolveProfile(ctx context.Context, profileID uint) (storage.Provider, error) {
+	var profile models.StorageProfile
+	if err := s.DB.WithContext(ctx).First(&profile, profileID).Error; err != nil {
+		return nil, err
+	}
+	return s.newProvider(ctx, &profile)
+}
+
+// BucketProfileIDs lists the profiles, retired or not, that write to a bucket
+// of their own instead of the default store
+func (s *StorageProfileService) BucketProfileIDs(ctx context.Context) ([]uint, error) {
+	var ids []uint
+	err := s.DB.WithContext(ctx).Model(&models.StorageProfile{}).
+		Where("bucket <> ''").
+		Order("id").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// newProvider connects to the profile's bucket, with the default store's
+// encryption on top, and scopes it to the profile's prefix
+func (s *StorageProfileService) newProvider(ctx context.Context, profile *models.StorageProfile) (storage.Provider, error) {
+	if profile.Bucket == "" {
+		// Kept under the profile's own key space, which orphan collection skips
+		area := strings.TrimSuffix(storage.ProfileKey(profile.ID, ""), "/")
+		return storage.NewPrefixedProvider(s.Default, path.Join(area, profile.Prefix)), nil
+	}
+
+	secretKey, err := s.openSecret(profile)
+	if err != nil {
+		return nil, err
+	}
+	store, err := storage.NewProvider(ctx, config.StorageConfig{
+		Driver:            profile.Driver,
+		Bucket:            profile.Bucket,
+		Endpoint:          profile.Endpoint,
+		Region:            profile.Region,
+		AccessKey:         profile.AccessKey,
+		SecretKey:         secretKey,
+		EncryptionKeys:    s.Storage.EncryptionKeys,
+		EncryptionKeyFile: s.Storage.EncryptionKeyFile,
+		// Checked again on every connection, in case the host's DNS changed
+		PublicEndpointOnly: !s.allowedHost(profile.Endpoint),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewPrefixedProvider(store, profile.Prefix), nil
+}
+
+// checkEndpoint keeps a user-supplied endpoint off the server's own network,
+// such as Redis, Postgres or cloud metadata. It must use https and resolve to
+// public addresses only, unless its host is allowed in the config.
+func (s *StorageProfileService) checkEndpoint(ctx context.Context, endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("%w: endpoint must be a URL", ErrInvalidStorageProfile)
+	}
+	if s.allowedHost(endpoint) {
+		return nil
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: endpoint must use https", ErrInvalidStorageProfile)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: endpoint host could not be resolved", ErrInvalidStorageProfile)
+	}
+	for _, addr := range addrs {
+		if !storage.IsPublicAddress(addr) {
+			return fmt.Errorf("%w: endpoint must not point at a private address", ErrInvalidStorageProfile)
+		}
+	}
+	return nil
+}
+
+// allowedHost reports whether the endpoint's host is exempt from the checks
+// on user-supplied endpoints
+func (s *StorageProfileService) allowedHost(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(s.Storage.ProfileAllowedHosts, func(host string) bool {
+		return strings.EqualFold(host, u.Hostname())
+	})
+}
+
+// checkBucket makes one request with the new credentials, so a typo is
+// reported now instead of on the first upload
+func (s *StorageProfileService) checkBucket(ctx context.Context, profile *models.StorageProfile) error {
+	store, err := s.newProvider(ctx, profile)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	_, err = store.Stat(ctx, ".meeting-assistant-check")
+	if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+		return fmt.Errorf("%w: %v", ErrBucketUnreachable, err)
+	}
+	return nil
+}
+
+// secretAAD binds a sealed secret to the bucket it belongs to
+func secretAAD(profile *models.StorageProfile) []byte {
+	return fmt.Appendf(nil, "storage-profile/%d/%s/%s", profile.UserID, profile.Endpoint, profile.Bucket)
+}
+
+func (s *StorageProfileService) sealSecret(profile *models.StorageProfile, secretKey string) error {
+	if s.Keys == nil {
+		return ErrCredentialsUnavailable
+	}
+	id, sealed, err := s.Keys.Wrap([]byte(secretKey), secretAAD(profile))
+	if err != nil {
+		return err
+	}
+	profile.SecretKeyID = id
+	profile.SecretKeySealed = sealed
+	return nil
+}
+
+func (s *StorageProfileService) openSecret(profile *models.StorageProfile) (string, error) {
+	if s.Keys == nil {
+		return "", ErrCredentialsUnavailable
+	}
+	secretKey, err := s.Keys.Unwrap(profile.SecretKeyID, profile.SecretKeySealed, secretAAD(profile))
+	if err != nil {
+		return "", err
+	}
+	return string(secretKey), nil
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
+	"gorm.io/gorm"
+)
+
+const (
+	// Files archived per sweep query
+	tieringBatchSize = 50
+	// A restore still running after this was lost to a restart and is retried on next access
+	restoreTimeout = time.Hour
+)
+
+// TieringReport summarises one tiering sweep
+type TieringReport struct {
+	Archived      int `json:"archived"`
+	Failed        int `json:"failed"`
+	StaleRestores int `json:"stale_restores"`
+}
+
+// TieringService moves recordings nobody has played for a while to the cold
+// tier, and brings them back when they are asked for again
+type TieringService struct {
+	DB *gorm.DB
+	// Nil when no cold tier is configured; access is still recorded
+	Tiered    *storage.TieredProvider
+	ColdAfter time.Duration
+}
+
+func NewTieringService(db *gorm.DB, tiered *storage.TieredProvider, coldAfter time.Duration) *TieringService {
+	return &TieringService{DB: db, Tiered: tiered, ColdAfter: coldAfter}
+}
+
+// Touch records a read of the object and starts restoring it if it is
+// archived. It returns the tier the object is in while the read is served.
+func (s *TieringService) Touch(key string) (models.StorageTier, error) {
+	var file models.File
+	err := s.DB.Where("key = ?", key).First(&file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.TierHot, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.DB.Model(&file).UpdateColumn("last_accessed_at", time.Now()).Error; err != nil {
+		return "", err
+	}
+	if file.Tier == models.TierArchived && s.Tiered != nil {
+		return s.requestRestore(&file)
+	}
+	return file.Tier, nil
+}
+
+// requestRestore marks the file as restoring and copies it back in the
+// background. Only the request that flips the tier starts the copy.
+func (s *TieringService) requestRestore(file *models.File) (models.StorageTier, error) {
+	result := s.DB.Model(&models.File{}).
+		Where("id = ? AND tier = ?", file.ID, models.TierArchived).
+		Updates(map[string]interface{}{
+			"tier":                 models.TierRestoring,
+			"restore_requested_at": time.Now(),
+		})
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 1 {
+		go s.restore(file.ID, file.Key)
+	}
+	return models.TierRestoring, nil
+}
+
+func (s *TieringService) restore(fileID uint, key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+	defer cancel()
+
+	if err := s.Tiered.Restore(ctx, key); err != nil {
+		log.Printf("Restore of file %d failed: %v", fileID, err)
+		s.DB.Model(&models.File{}).
+			Where("id = ? AND tier = ?", fileID, models.TierRestoring).
+			Update("tier", models.TierArchived)
+		return
+	}
+
+	err := s.DB.Model(&models.File{}).Where("id = ?", fileID).Updates(map[string]interface{}{
+		"tier":                 models.TierHot,
+		"archived_at":          nil,
+		"restore_requested_at": nil,
+	}).Error
+	if err != nil {
+		log.Printf("Failed to record restore of file %d: %v", fileID, err)
+		return
+	}
+	log.Printf("Restored file %d from the cold tier", fileID)
+}
+
+// Sweep archives recordings in use by a meeting that are older than ColdAfter
+// and haven't been read within it. Files in storage profiles stay where their
+// owner put them.
+func (s *TieringService) Sweep(ctx context.Context) (*TieringReport, error) {
+	report := &TieringReport{}
+	if s.Tiered == nil {
+		return report, nil
+	}
+	now := time.Now()
+
+	// Restores interrupted by a restart; the object is still in the cold tier
+	result := s.DB.WithContext(ctx).Model(&models.File{}).
+		Where("tier = ? AND restore_requested_at < ?", models.TierRestoring, now.Add(-restoreTimeout)).
+		Update("tier", models.TierArchived)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	report.StaleRestores = int(result.RowsAffected)
+
+	cutoff := now.Add(-s.ColdAfter)
+	lastID := uint(0)
+	for {
+		var files []models.File
+		err := s.DB.WithContext(ctx).
+			Where("id > ? AND tier = ? AND storage_profile_id = 0 AND ref_count > 0", lastID, models.TierHot).
+			Where("created_at < ? AND (last_accessed_at IS NULL OR last_accessed_at < ?)", cutoff, cutoff).
+			Order("id").
+			Limit(tieringBatchSize).
+			Find(&files).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return report, nil
+		}
+
+		for _, file := range files {
+			lastID = file.ID
+			if err := s.archive(ctx, &file); err != nil {
+				log.Printf("Failed to archive file %d: %v", file.ID, err)
+				report.Failed++
+				continue
+			}
+			report.Archived++
+		}
+	}
+}
+
+func (s *TieringService) archive(ctx context.Context, file *models.File) error {
+	if err := s.Tiered.Archive(ctx, file.Key); err != nil {
+		return err
+	}
+	// Archive is safe to repeat, so a failure here is fixed by the next sweep
+	return s.DB.WithContext(ctx).Model(&models.File{}).
+		Where("id = ? AND tier = ?", file.ID, models.TierHot).
+		Updates(map[string]interface{}{
+			"tier":        models.TierArchived,
+			"archived_at": time.Now(),
+		}).Error
+}
+
+// RunSweeper calls Sweep every interval until ctx is done
+func (s *TieringService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.Sweep(ctx)
+			if err != nil {
+				log.Printf("Tiering sweep failed: %v", err)
+				continue
+			}
+			if report.Archived > 0 || report.Failed > 0 {
+				log.Printf("Tiering sweep archived %d recordings, %d failed", report.Archived, report.Failed)
+			}
+		}
+	}
+}
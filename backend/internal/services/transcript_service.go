@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/models"
+	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNoTranscript means the meeting has no transcript yet, or it has expired
+var ErrNoTranscript = errors.New("meeting has no transcript")
+
+const transcriptContentType = "text/plain; charset=utf-8"
+
+// TranscriptService keeps small transcripts in the meetings table and moves
+// larger ones to object storage, leaving their key in transcript_key
+type TranscriptService struct {
+	DB    *gorm.DB
+	Store storage.Provider
+	// Transcripts larger than this are offloaded; zero keeps every transcript in Postgres
+	OffloadBytes int64
+}
+
+func NewTranscriptService(db *gorm.DB, store storage.Provider, offloadBytes int64) *TranscriptService {
+	return &TranscriptService{DB: db, Store: store, OffloadBytes: offloadBytes}
+}
+
+// TranscriptKey is where a meeting's transcript is offloaded to. It is kept in
+// the same storage profile as the recording.
+func TranscriptKey(meeting *models.Meeting) string {
+	var profileID uint
+	if meeting.RecordingPath != nil {
+		profileID, _, _ = storage.ParseProfileKey(*meeting.RecordingPath)
+	}
+	return storage.ProfileKey(profileID, fmt.Sprintf("transcripts/%d.txt", meeting.ID))
+}
+
+// Save stores a new transcript for the meeting, inline or in storage depending
+// on its size. The meeting row is locked while the object is written, so a
+// concurrent save or offload can't leave the pointer at stale text.
+func (s *TranscriptService) Save(ctx context.Context, meetingID uint, text string) error {
+	return s.save(ctx, meetingID, &text)
+}
+
+// save stores text as the meeting's transcript. A nil text re-saves the inline
+// transcript as read under the lock, which offloads it if it is too large.
+func (s *TranscriptService) save(ctx context.Context, meetingID uint, text *string) error {
+	var stale string
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var meeting models.Meeting
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status <> ?", models.StatusDeleting).
+			First(&meeting, meetingID).Error
+		if err != nil {
+			return err
+		}
+
+		if text == nil {
+			if meeting.Transcript == nil {
+				return nil
+			}
+			text = meeting.Transcript
+		}
+
+		size := int64(len(*text))
+		updates := map[string]interface{}{
+			"transcript_size_bytes": size,
+			"transcript_expired_at": nil,
+		}
+		if s.OffloadBytes > 0 && size > s.OffloadBytes {
+			key := TranscriptKey(&meeting)
+			if _, err := s.Store.Upload(ctx, strings.NewReader(*text), key, transcriptContentType); err != nil {
+				return fmt.Errorf("failed to store transcript: %w", err)
+			}
+			updates["transcript"] = nil
+			updates["transcript_key"] = key
+			if meeting.TranscriptKey != nil && *meeting.TranscriptKey != key {
+				stale = *meeting.TranscriptKey
+			}
+		} else {
+			updates["transcript"] = *text
+			updates["transcript_key"] = nil
+			if meeting.TranscriptKey != nil {
+				stale = *meeting.TranscriptKey
+			}
+		}
+		return tx.Model(&meeting).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// The orphan collector picks up the old object if this fails
+	if stale != "" {
+		if err := s.Store.Delete(ctx, stale); err != nil {
+			log.Printf("Failed to delete replaced transcript %s: %v", stale, err)
+		}
+	}
+	return nil
+}
+
+// Open reads the meeting's transcript from wherever it is stored
+func (s *TranscriptService) Open(ctx context.Context, meeting *models.Meeting) (io.ReadCloser, error) {
+	switch {
+	case meeting.TranscriptKey != nil:
+		return s.Store.Open(ctx, *meeting.TranscriptKey, 0, -1)
+	case meeting.Transcript != nil:
+		return io.NopCloser(strings.NewReader(*meeting.Transcript)), nil
+	}
+	return nil, ErrNoTranscript
+}
+
+// Sweep offloads inline transcripts above the threshold, such as ones a worker
+// wrote to the database directly or that predate offloading. It returns how
+// many were moved.
+func (s *TranscriptService) Sweep(ctx context.Context) (int, error) {
+	if s.OffloadBytes <= 0 {
+		return 0, nil
+	}
+
+	moved := 0
+	var meetings []models.Meeting
+	err := s.DB.WithContext(ctx).
+		Select("id").
+		Where("status <> ? AND transcript IS NOT NULL AND octet_length(transcript) > ?", models.StatusDeleting, s.OffloadBytes).
+		FindInBatches(&meetings, 100, func(tx *gorm.DB, batch int) error {
+			for _, meeting := range meetings {
+				if err := s.save(ctx, meeting.ID, nil); err != nil {
+					log.Printf("Failed to offload transcript of meeting %d: %v", meeting.ID, err)
+					continue
+				}
+				moved++
+			}
+			return nil
+		}).Error
+	return moved, err
+}
+
+// RunSweeper calls Sweep every interval until ctx is done
+func (s *TranscriptService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			moved, err := s.Sweep(ctx)
+			if err != nil {
+				log.Printf("Transcript offload failed: %v", err)
+			}
+			if moved > 0 {
+				log.Printf("Offloaded %d transcripts to storage", moved)
+			}
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jaykapade/meeting-assistant/backend/internal/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrUploadSessionNotFound = errors.New("upload session not found")
+	ErrUploadOffsetMismatch  = errors.New("upload offset mismatch")
+	ErrUploadSessionLocked   = errors.New("upload session is busy")
+)
+
+// Sessions are dropped if the client doesn't resume within this window
+const uploadSessionTTL = 24 * time.Hour
+
+// A chunk must be written within this long, or another request may take over the session
+const uploadSessionLockTTL = 10 * time.Minute
+
+// unlockScript releases a session lock only if the caller still holds it,
+// so a request whose lock expired can't release the next holder's
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// UploadSession tracks a resumable upload that is assembled through multipart storage
+type UploadSession struct {
+	ID          string                  `json:"id"`
+	Key         string                  `json:"key"`
+	UploadID    string                  `json:"upload_id"`
+	Filename    string                  `json:"filename"`
+	ContentType string                  `json:"content_type"`
+	Length      int64                   `json:"length"`
+	Offset      int64                   `json:"offset"`
+	Parts       []storage.CompletedPart `json:"parts"`
+	Completed   bool                    `json:"completed"`
+}
+
+type UploadSessionService struct {
+	Client *redis.Client
+	Store  storage.Provider
+}
+
+func NewUploadSessionService(addr string, store storage.Provider) *UploadSessionService {
+	client := redis.NewClient(&redis.Options{
+		Addr: addr, // e.g., "localhost:6379"
+	})
+	return &UploadSessionService{Client: client, Store: store}
+}
+
+func sessionKey(id string) string { return "upload_session:" + id }
+func tailKey(id string) string    { return "upload_session:" + id + ":tail" }
+func lockKey(id string) string    { return "upload_session:" + id + ":lock" }
+
+// Create starts a multipart upload for a new object under key
+func (s *UploadSessionService) Create(ctx context.Context, key, filename, contentType string, length int64) (*UploadSession, error) {
+	uploadID, err := s.Store.CreateMultipartUpload(ctx, key, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &UploadSession{
+		ID:          uuid.New().String(),
+		Key:         key,
+		UploadID:    uploadID,
+		Filename:    filename,
+		ContentType: contentType,
+		Length:      length,
+	}
+	if err := s.save(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *UploadSessionService) Get(ctx context.Context, id string) (*UploadSession, error) {
+	raw, err := s.Client.Get(ctx, sessionKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrUploadSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load upload session: %v", err)
+	}
+
+	var session UploadSession
+	if err := json.Unmarshal(raw, &session); err != nil {
+		return nil, fmt.Errorf("failed to decode upload session: %v", err)
+	}
+	return &session, nil
+}
+
+// WriteChunk appends body at offset. Bytes are cut into MinPartSize parts;
+// whatever is left over is kept in Redis until the next chunk arrives, so
+// the reported offset always covers every byte the client has sent.
+func (s *UploadSessionService) WriteChunk(ctx context.Context, id string, offset int64, body io.Reader) (*UploadSession, error) {
+	// Only one request may append to a session at a time
+	token := uuid.NewString()
+	locked, err := s.Client.SetNX(ctx, lockKey(id), token, uploadSessionLockTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock upload session: %v", err)
+	}
+	if !locked {
+		return nil, ErrUploadSessionLocked
+	}
+	defer unlockScript.Run(context.Background(), s.Client, []string{lockKey(id)}, token)
+
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != session.Offset || session.Completed {
+		return session, ErrUploadOffsetMismatch
+	}
+
+	tail, err := s.Client.Get(ctx, tailKey(id)).Bytes()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to load pending bytes: %v", err)
+	}
+
+	src := io.MultiReader(bytes.NewReader(tail), io.LimitReader(body, session.Length-session.Offset))
+	buf := make([]byte, storage.MinPartSize)
+	stored := partsSize(session.Parts)
+	var pending []byte
+	var readErr, writeErr error
+
+	for {
+		n, err := io.ReadFull(src, buf)
+		final := stored+int64(n) == session.Length
+
+		if n > 0 && (n == len(buf) || final) {
+			part, err := s.Store.UploadPart(ctx, session.Key, session.UploadID, len(session.Parts)+1, bytes.NewReader(buf[:n]), int64(n))
+			if err != nil {
+				// Keep the bytes so the client doesn't have to resend them
+				pending = append([]byte(nil), buf[:n]...)
+				writeErr = err
+				break
+			}
+			session.Parts = append(session.Parts, *part)
+			stored += int64(n)
+		} else if n > 0 {
+			pending = append([]byte(nil), buf[:n]...)
+		}
+
+		if err != nil {
+			// EOF just means this chunk is done, anything else is a dropped connection
+			if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				readErr = err
+			}
+			break
+		}
+	}
+
+	session.Offset = stored + int64(len(pending))
+
+	// Progress must be persisted even if the client went away mid-chunk
+	ctx = context.WithoutCancel(ctx)
+
+	if session.Offset == session.Length && writeErr == nil {
+		if _, err := s.Store.CompleteMultipartUpload(ctx, session.Key, session.UploadID, session.Parts); err != nil {
+			writeErr = err
+		} else {
+			session.Completed = true
+		}
+	}
+
+	// A request that outlived its lock must not overwrite the new holder's progress
+	holder, err := s.Client.Get(ctx, lockKey(id)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to check upload session lock: %v", err)
+	}
+	if holder != token {
+		return nil, ErrUploadSessionLocked
+	}
+
+	if err := s.saveTail(ctx, id, pending); err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, session); err != nil {
+		return nil, err
+	}
+
+	if writeErr != nil {
+		return session, writeErr
+	}
+	return session, readErr
+}
+
+// Abort discards the session and any parts stored so far
+func (s *UploadSessionService) Abort(ctx context.Context, id string) error {
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !session.Completed {
+		if err := s.Store.AbortMultipartUpload(ctx, session.Key, session.UploadID); err != nil {
+			return err
+		}
+	}
+	return s.Client.Del(ctx, sessionKey(id), tailKey(id)).Err()
+}
+
+func (s *UploadSessionService) save(ctx context.Context, session *UploadSession) error {
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal upload session: %v", err)
+	}
+
+	err = s.Client.Set(ctx, sessionKey(session.ID), raw, uploadSessionTTL).Err()
+	if err != nil {
+		return fmt.Errorf("failed to save upload session: %v", err)
+	}
+	return nil
+}
+
+func (s *UploadSessionService) saveTail(ctx context.Context, id string, pending []byte) error {
+	var err error
+	if len(pending) == 0 {
+		err = s.Client.Del(ctx, tailKey(id)).Err()
+	} else {
+		err = s.Client.Set(ctx, tailKey(id), pending, uploadSessionTTL).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save pending bytes: %v", err)
+	}
+	return nil
+}
+
+func partsSize(parts []storage.CompletedPart) int64 {
+	var size int64
+	for _, part := range parts {
+		size += part.Size
+	}
+	return size
+}
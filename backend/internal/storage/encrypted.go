@@ -0,0 +1,611 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// ErrDirectAccessUnsupported is returned for presigned URLs, which would hand
+// clients ciphertext or let them store plaintext
+var ErrDirectAccessUnsupported = errors.New("direct access is not available for encrypted storage")
+
+const (
+	// Plaintext bytes sealed per AES-GCM chunk
+	encryptionChunkSize = 64 << 10
+	// Random bytes at the start of every part, mixed into its nonces
+	partSaltSize = 4
+	// Suffix of the object that holds the wrapped data key
+	envelopeSuffix = ".dek"
+)
+
+// envelope is stored next to each encrypted object. Parts is the plaintext
+// size of every part in order; it is nil while a multipart upload is running.
+type envelope struct {
+	Version    int     `json:"version"`
+	KeyID      string  `json:"key_id"`
+	WrappedKey []byte  `json:"wrapped_key"`
+	ChunkSize  int64   `json:"chunk_size"`
+	Parts      []int64 `json:"parts"`
+}
+
+func (e *envelope) size() int64 {
+	var size int64
+	for _, part := range e.Parts {
+		size += part
+	}
+	return size
+}
+
+// aad binds the wrapped key to the object and its layout, so an envelope can't
+// be moved to another key or have its sizes changed to truncate the object
+func (e *envelope) aad(key string) []byte {
+	layout := "pending"
+	if e.Parts != nil {
+		sizes := make([]string, len(e.Parts))
+		for i, part := range e.Parts {
+			sizes[i] = fmt.Sprint(part)
+		}
+		layout = strings.Join(sizes, ",")
+	}
+	return []byte(fmt.Sprintf("v%d\n%s\n%d\n%s", e.Version, key, e.ChunkSize, layout))
+}
+
+// EncryptedProvider encrypts objects before they reach the wrapped provider.
+// Every object gets its own AES-256 data key, wrapped by a key-encryption key
+// from the Keyring and stored in "<key>.dek". Objects are sealed in chunks
+// so ranged reads only decrypt the chunks they touch.
+//
+// Layout of each part: a random salt, then chunks of ChunkSize plaintext
+// plus a GCM tag. A chunk's nonce is part number | salt | chunk index.
+//
+// Objects without an envelope were stored before encryption was enabled and
+// are read as plaintext.
+type EncryptedProvider struct {
+	inner Provider
+	keys  *Keyring
+}
+
+var _ Provider = (*EncryptedProvider)(nil)
+
+func NewEncryptedProvider(inner Provider, keys *Keyring) *EncryptedProvider {
+	log.Printf("Encryption at rest enabled with key %s", keys.ActiveID())
+	return &EncryptedProvider{inner: inner, keys: keys}
+}
+
+// Unwrap returns the provider that holds the ciphertext
+func (p *EncryptedProvider) Unwrap() Provider {
+	return p.inner
+}
+
+func (p *EncryptedProvider) Upload(ctx context.Context, file io.Reader, key string, contentType string) (*UploadResult, error) {
+	env, dataKey, err := newEnvelope()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypter := newEncryptReader(aead, 1, env.ChunkSize, file)
+	result, err := p.inner.Upload(ctx, encrypter, key, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	env.Parts = []int64{encrypter.plaintext}
+	if err := p.saveEnvelope(ctx, key, env, dataKey); err != nil {
+		p.inner.Delete(context.Background(), key)
+		return nil, err
+	}
+
+	return &UploadResult{
+		Key:      key,
+		URL:      result.URL,
+		Size:     encrypter.plaintext,
+		MimeType: contentType,
+	}, nil
+}
+
+func (p *EncryptedProvider) Delete(ctx context.Context, key string) error {
+	if err := p.inner.Delete(ctx, key); err != nil {
+		return err
+	}
+	return p.inner.Delete(ctx, key+envelopeSuffix)
+}
+
+// Copy duplicates the ciphertext and re-wraps the data key for the new key
+func (p *EncryptedProvider) Copy(ctx context.Context, srcKey string, dstKey string) error {
+	env, dataKey, err := p.loadEnvelope(ctx, srcKey)
+	if errors.Is(err, ErrObjectNotFound) {
+		return p.inner.Copy(ctx, srcKey, dstKey)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := p.inner.Copy(ctx, srcKey, dstKey); err != nil {
+		return err
+	}
+	return p.saveEnvelope(ctx, dstKey, env, dataKey)
+}
+
+func (p *EncryptedProvider) GetSignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error) {
+	return "", ErrDirectAccessUnsupported
+}
+
+func (p *EncryptedProvider) GetSignedUploadURL(ctx context.Context, key string, contentType string, size int64, ttl time.Duration) (*SignedUpload, error) {
+	return nil, ErrDirectAccessUnsupported
+}
+
+// Stat reports the plaintext size
+func (p *EncryptedProvider) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := p.inner.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	env, _, err := p.loadEnvelope(ctx, key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return info, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info.Size = env.size()
+	return info, nil
+}
+
+func (p *EncryptedProvider) Open(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	env, dataKey, err := p.loadEnvelope(ctx, key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return p.inner.Open(ctx, key, offset, length)
+	}
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	size := env.size()
+	if offset < 0 || offset > size {
+		return nil, fmt.Errorf("offset %d is outside the object", offset)
+	}
+	if length < 0 || offset+length > size {
+		length = size - offset
+	}
+	if length == 0 {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	d := &decryptReader{aead: aead, env: env}
+	from, to := d.locate(offset, offset+length)
+
+	// Starting mid-part, the salt has to be fetched from the start of the part
+	if d.chunk > 0 {
+		salt, err := p.readRange(ctx, key, d.partStart(d.part), partSaltSize)
+		if err != nil {
+			return nil, err
+		}
+		copy(d.salt[:], salt)
+		d.haveSalt = true
+	}
+
+	body, err := p.inner.Open(ctx, key, from, to-from)
+	if err != nil {
+		return nil, err
+	}
+	d.src = body
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(d, length), body}, nil
+}
+
+// List hides envelopes; sizes are those of the stored ciphertext
+func (p *EncryptedProvider) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	return p.inner.List(ctx, prefix, func(object ObjectInfo) error {
+		if strings.HasSuffix(object.Key, envelopeSuffix) {
+			return nil
+		}
+		return fn(object)
+	})
+}
+
+// CreateMultipartUpload stores a pending envelope so each part can be
+// encrypted with the same data key as it arrives
+func (p *EncryptedProvider) CreateMultipartUpload(ctx context.Context, key string, contentType string) (string, error) {
+	env, dataKey, err := newEnvelope()
+	if err != nil {
+		return "", err
+	}
+	if err := p.saveEnvelope(ctx, key, env, dataKey); err != nil {
+		return "", err
+	}
+
+	uploadID, err := p.inner.CreateMultipartUpload(ctx, key, contentType)
+	if err != nil {
+		p.inner.Delete(context.Background(), key+envelopeSuffix)
+		return "", err
+	}
+	return uploadID, nil
+}
+
+func (p *EncryptedProvider) UploadPart(ctx context.Context, key string, uploadID string, number int, part io.ReadSeeker, size int64) (*CompletedPart, error) {
+	env, dataKey, err := p.loadEnvelope(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if env.Parts != nil {
+		return nil, fmt.Errorf("object %s is not being uploaded", key)
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parts are small enough to seal in memory, and the inner provider needs the exact size
+	encrypter := newEncryptReader(aead, number, env.ChunkSize, io.LimitReader(part, size))
+	sealed, err := io.ReadAll(encrypter)
+	if err != nil {
+		return nil, err
+	}
+
+	completed, err := p.inner.UploadPart(ctx, key, uploadID, number, bytes.NewReader(sealed), int64(len(sealed)))
+	if err != nil {
+		return nil, err
+	}
+	return &CompletedPart{Number: number, ETag: completed.ETag, Size: encrypter.plaintext}, nil
+}
+
+func (p *EncryptedProvider) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []CompletedPart) (*UploadResult, error) {
+	env, dataKey, err := p.loadEnvelope(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := make([]CompletedPart, len(parts))
+	env.Parts = make([]int64, len(parts))
+	for i, part := range parts {
+		// Reads derive each part's nonces from its position
+		if part.Number != i+1 {
+			return nil, fmt.Errorf("parts must be numbered 1 to %d in order", len(parts))
+		}
+		sealed[i] = part
+		sealed[i].Size = sealedPartSize(part.Size, env.ChunkSize)
+		env.Parts[i] = part.Size
+	}
+
+	result, err := p.inner.CompleteMultipartUpload(ctx, key, uploadID, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-wrap with the final layout, which also moves it to the active key
+	if err := p.saveEnvelope(ctx, key, env, dataKey); err != nil {
+		return nil, err
+	}
+
+	result.Size = env.size()
+	return result, nil
+}
+
+func (p *EncryptedProvider) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	if err := p.inner.AbortMultipartUpload(ctx, key, uploadID); err != nil {
+		return err
+	}
+	return p.inner.Delete(ctx, key+envelopeSuffix)
+}
+
+// RotationReport summarises a RotateKeys run
+type RotationReport struct {
+	Scanned   int `json:"scanned"`
+	Rewrapped int `json:"rewrapped"`
+	Failed    int `json:"failed"`
+}
+
+// RotateKeys re-wraps every data key that isn't under the active KEK. Only the
+// small envelopes are rewritten; the objects themselves are untouched. Once it
+// reports no failures, retired keys can be removed from the keyring.
+func (p *EncryptedProvider) RotateKeys(ctx context.Context) (*RotationReport, error) {
+	report := &RotationReport{}
+
+	err := p.inner.List(ctx, "", func(object ObjectInfo) error {
+		if !strings.HasSuffix(object.Key, envelopeSuffix) {
+			return nil
+		}
+		report.Scanned++
+
+		key := strings.TrimSuffix(object.Key, envelopeSuffix)
+		env, dataKey, err := p.loadEnvelope(ctx, key)
+		if err != nil {
+			log.Printf("Failed to read data key for %s: %v", key, err)
+			report.Failed++
+			return nil
+		}
+
+		// Pending uploads are re-wrapped with the active key when they complete
+		if env.KeyID == p.keys.ActiveID() || env.Parts == nil {
+			return nil
+		}
+
+		if err := p.saveEnvelope(ctx, key, env, dataKey); err != nil {
+			log.Printf("Failed to re-wrap data key for %s: %v", key, err)
+			report.Failed++
+			return nil
+		}
+		report.Rewrapped++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// newEnvelope generates a fresh data key for an object
+func newEnvelope() (*envelope, []byte, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, err
+	}
+	return &envelope{Version: 1, ChunkSize: encryptionChunkSize}, dataKey, nil
+}
+
+// loadEnvelope reads the envelope for key and unwraps its data key
+func (p *EncryptedProvider) loadEnvelope(ctx context.Context, key string) (*envelope, []byte, error) {
+	raw, err := p.readRange(ctx, key+envelopeSuffix, 0, -1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode envelope for %s: %w", key, err)
+	}
+	if env.Version != 1 || env.ChunkSize <= 0 {
+		return nil, nil, fmt.Errorf("unsupported envelope for %s", key)
+	}
+
+	dataKey, err := p.keys.Unwrap(env.KeyID, env.WrappedKey, env.aad(key))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &env, dataKey, nil
+}
+
+// saveEnvelope wraps the data key with the active KEK for the envelope's current layout
+func (p *EncryptedProvider) saveEnvelope(ctx context.Context, key string, env *envelope, dataKey []byte) error {
+	var err error
+	env.KeyID, env.WrappedKey, err = p.keys.Wrap(dataKey, env.aad(key))
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	_, err = p.inner.Upload(ctx, bytes.NewReader(raw), key+envelopeSuffix, "application/json")
+	return err
+}
+
+func (p *EncryptedProvider) readRange(ctx context.Context, key string, offset int64, length int64) ([]byte, error) {
+	body, err := p.inner.Open(ctx, key, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// sealedPartSize is the stored size of a part holding size plaintext bytes
+func sealedPartSize(size int64, chunkSize int64) int64 {
+	return partSaltSize + size + chunkCount(size, chunkSize)*gcmTagSize
+}
+
+// chunkCount is how many chunks a part is sealed in; even an empty part has one
+func chunkCount(size int64, chunkSize int64) int64 {
+	if size == 0 {
+		return 1
+	}
+	return (size + chunkSize - 1) / chunkSize
+}
+
+const gcmTagSize = 16
+
+func chunkNonce(part int, salt [partSaltSize]byte, chunk int64) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint32(nonce[0:4], uint32(part))
+	copy(nonce[4:8], salt[:])
+	binary.BigEndian.PutUint32(nonce[8:12], uint32(chunk))
+	return nonce
+}
+
+// encryptReader seals src as one part: the salt, then one chunk per chunkSize bytes
+type encryptReader struct {
+	aead      cipher.AEAD
+	part      int
+	chunkSize int64
+	src       io.Reader
+	salt      [partSaltSize]byte
+
+	chunk     int64
+	buf       []byte
+	out       []byte
+	done      bool
+	err       error
+	plaintext int64
+}
+
+func newEncryptReader(aead cipher.AEAD, part int, chunkSize int64, src io.Reader) *encryptReader {
+	r := &encryptReader{aead: aead, part: part, chunkSize: chunkSize, src: src, buf: make([]byte, chunkSize)}
+	if _, err := rand.Read(r.salt[:]); err != nil {
+		r.err = err
+	}
+	r.out = append([]byte(nil), r.salt[:]...)
+	return r
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(r.src, r.buf)
+		switch {
+		case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+			r.done = true
+			if n == 0 && r.chunk > 0 {
+				return 0, io.EOF
+			}
+		case err != nil:
+			r.err = err
+			return 0, err
+		}
+
+		r.out = r.aead.Seal(r.out[:0], chunkNonce(r.part, r.salt, r.chunk), r.buf[:n], nil)
+		r.chunk++
+		r.plaintext += int64(n)
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// decryptReader opens the chunks of a ranged read, crossing into later parts
+type decryptReader struct {
+	aead cipher.AEAD
+	env  *envelope
+	src  io.Reader
+
+	part     int   // Index into env.Parts
+	chunk    int64 // Chunk within the part
+	salt     [partSaltSize]byte
+	haveSalt bool
+	skip     int64 // Plaintext to drop from the first chunk
+	out      []byte
+	buf      []byte
+}
+
+// locate positions the reader at the chunk holding offset and returns the
+// stored byte range covering plaintext [offset, end)
+func (d *decryptReader) locate(offset int64, end int64) (int64, int64) {
+	chunkSize := d.env.ChunkSize
+
+	part, within := d.find(offset)
+	d.part = part
+	d.chunk = within / chunkSize
+	d.skip = within - d.chunk*chunkSize
+
+	from := d.partStart(part)
+	if d.chunk > 0 {
+		from += partSaltSize + d.chunk*(chunkSize+gcmTagSize)
+	}
+
+	lastPart, lastWithin := d.find(end - 1)
+	lastChunk := lastWithin / chunkSize
+	chunkEnd := min((lastChunk+1)*chunkSize, d.env.Parts[lastPart])
+	to := d.partStart(lastPart) + partSaltSize + chunkEnd + (lastChunk+1)*gcmTagSize
+
+	return from, to
+}
+
+// find returns the part holding a plaintext offset and the offset within it
+func (d *decryptReader) find(offset int64) (int, int64) {
+	for i, size := range d.env.Parts {
+		if offset < size || i == len(d.env.Parts)-1 {
+			return i, offset
+		}
+		offset -= size
+	}
+	return 0, offset
+}
+
+// partStart is where a part begins in the stored object
+func (d *decryptReader) partStart(part int) int64 {
+	var start int64
+	for _, size := range d.env.Parts[:part] {
+		start += sealedPartSize(size, d.env.ChunkSize)
+	}
+	return start
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.part >= len(d.env.Parts) {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// next decrypts the chunk at the current position and advances past it
+func (d *decryptReader) next() error {
+	size := d.env.Parts[d.part]
+	chunkSize := d.env.ChunkSize
+
+	if !d.haveSalt {
+		if _, err := io.ReadFull(d.src, d.salt[:]); err != nil {
+			return unexpectedEOF(err)
+		}
+		d.haveSalt = true
+	}
+
+	plain := min(chunkSize, size-d.chunk*chunkSize)
+	if cap(d.buf) < int(plain+gcmTagSize) {
+		d.buf = make([]byte, chunkSize+gcmTagSize)
+	}
+	sealed := d.buf[:plain+gcmTagSize]
+	if _, err := io.ReadFull(d.src, sealed); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	// The nonce is the part number, which starts at 1
+	out, err := d.aead.Open(sealed[:0], chunkNonce(d.part+1, d.salt, d.chunk), sealed, nil)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt chunk %d of part %d: %w", d.chunk, d.part+1, err)
+	}
+	d.out = out[d.skip:]
+	d.skip = 0
+
+	d.chunk++
+	if d.chunk >= chunkCount(size, chunkSize) {
+		d.part++
+		d.chunk = 0
+		d.haveSalt = false
+	}
+	return nil
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,394 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+const chunk = encryptionChunkSize
+
+// newKey returns a keyring entry with a fresh random key
+func newKey(t *testing.T, id string) string {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return id + ":" + base64.StdEncoding.EncodeToString(key)
+}
+
+func newKeyring(t *testing.T, entries ...string) *Keyring {
+	t.Helper()
+	keys, err := ParseKeyring(strings.Join(entries, ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func newEncrypted(t *testing.T) (*EncryptedProvider, *LocalDiskProvider) {
+	t.Helper()
+	inner := newLocal(t)
+	return NewEncryptedProvider(inner, newKeyring(t, newKey(t, "k1"))), inner
+}
+
+// plaintext is size bytes that differ from chunk to chunk, so a read from the
+// wrong place can't pass
+func plaintext(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i*7 + i/chunk)
+	}
+	return data
+}
+
+func readRange(t *testing.T, store Provider, key string, offset, length int64) []byte {
+	t.Helper()
+	body, err := store.Open(context.Background(), key, offset, length)
+	if err != nil {
+		t.Fatalf("open %s at %d+%d: %v", key, offset, length, err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("read %s at %d+%d: %v", key, offset, length, err)
+	}
+	return data
+}
+
+// uploadParts stores data as a multipart upload split at the given sizes
+func uploadParts(t *testing.T, store Provider, key string, data []byte, sizes []int) {
+	t.Helper()
+	ctx := context.Background()
+	uploadID, err := store.CreateMultipartUpload(ctx, key, "audio/mpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []CompletedPart
+	for i, size := range sizes {
+		part, err := store.UploadPart(ctx, key, uploadID, i+1, bytes.NewReader(data[:size]), int64(size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, *part)
+		data = data[size:]
+	}
+	if _, err := store.CompleteMultipartUpload(ctx, key, uploadID, parts); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"empty", 0},
+		{"one byte", 1},
+		{"just under a chunk", chunk - 1},
+		{"exactly a chunk", chunk},
+		{"just over a chunk", chunk + 1},
+		{"several chunks", 3*chunk + 17},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, inner := newEncrypted(t)
+			data := plaintext(tt.size)
+
+			result, err := store.Upload(ctx, bytes.NewReader(data), "a.mp3", "audio/mpeg")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Size != int64(tt.size) {
+				t.Fatalf("Upload size = %d, want %d", result.Size, tt.size)
+			}
+			if got := readRange(t, store, "a.mp3", 0, -1); !bytes.Equal(got, data) {
+				t.Fatalf("read %d bytes back, want the %d uploaded", len(got), tt.size)
+			}
+
+			info, err := store.Stat(ctx, "a.mp3")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size != int64(tt.size) {
+				t.Fatalf("Stat size = %d, want the plaintext size %d", info.Size, tt.size)
+			}
+
+			stored := readRange(t, inner, "a.mp3", 0, -1)
+			if int64(len(stored)) != sealedPartSize(int64(tt.size), chunk) {
+				t.Fatalf("stored %d bytes, want %d", len(stored), sealedPartSize(int64(tt.size), chunk))
+			}
+			// Long enough that it can't turn up in the ciphertext by chance
+			if tt.size >= 16 && bytes.Contains(stored, data[:min(tt.size, 64)]) {
+				t.Fatal("plaintext reached the inner store")
+			}
+		})
+	}
+}
+
+func TestEncryptedRangedReads(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newEncrypted(t)
+	size := int64(3*chunk + 100)
+	data := plaintext(int(size))
+	if _, err := store.Upload(ctx, bytes.NewReader(data), "a.mp3", "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		offset, length int64
+	}{
+		{0, 1},
+		{0, chunk},
+		{1, chunk},
+		{chunk - 1, 1},
+		{chunk - 1, 2},
+		{chunk, 1},
+		{chunk, chunk},
+		{chunk - 1, chunk + 2},
+		{chunk + 5, 2*chunk + 10},
+		{3 * chunk, 100},
+		{size - 1, 1},
+		{size - 1, -1},
+		{5, -1},
+		{10, size}, // clamped to the end
+		{size, -1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d+%d", tt.offset, tt.length), func(t *testing.T) {
+			end := size
+			if tt.length >= 0 {
+				end = min(tt.offset+tt.length, size)
+			}
+			if got := readRange(t, store, "a.mp3", tt.offset, tt.length); !bytes.Equal(got, data[tt.offset:end]) {
+				t.Fatalf("read %d bytes, want %d from offset %d", len(got), end-tt.offset, tt.offset)
+			}
+		})
+	}
+
+	if _, err := store.Open(ctx, "a.mp3", size+1, -1); err == nil {
+		t.Fatal("opened past the end of the object")
+	}
+}
+
+func TestEncryptedMultipart(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newEncrypted(t)
+
+	// Uneven parts, so part and chunk boundaries don't line up
+	sizes := []int{chunk + 5, 3, 2*chunk - 1, chunk}
+	total := 0
+	for _, size := range sizes {
+		total += size
+	}
+	data := plaintext(total)
+	uploadParts(t, store, "a.mp3", data, sizes)
+
+	info, err := store.Stat(ctx, "a.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != int64(total) {
+		t.Fatalf("Stat size = %d, want %d", info.Size, total)
+	}
+	if got := readRange(t, store, "a.mp3", 0, -1); !bytes.Equal(got, data) {
+		t.Fatal("multipart object did not read back whole")
+	}
+
+	// Reads around each part boundary, and across all of them
+	var ranges [][2]int64
+	boundary := int64(0)
+	for _, size := range sizes[:len(sizes)-1] {
+		boundary += int64(size)
+		ranges = append(ranges,
+			[2]int64{boundary - 1, 1},
+			[2]int64{boundary - 1, 2},
+			[2]int64{boundary, 1},
+			[2]int64{boundary, -1},
+		)
+	}
+	ranges = append(ranges,
+		[2]int64{chunk, 1},         // Second chunk of the first part
+		[2]int64{chunk + 6, chunk}, // Middle of the third part
+		[2]int64{1, int64(total) - 2},
+	)
+	for _, r := range ranges {
+		offset, length := r[0], r[1]
+		t.Run(fmt.Sprintf("%d+%d", offset, length), func(t *testing.T) {
+			end := int64(total)
+			if length >= 0 {
+				end = offset + length
+			}
+			if got := readRange(t, store, "a.mp3", offset, length); !bytes.Equal(got, data[offset:end]) {
+				t.Fatalf("read %d bytes, want %d from offset %d", len(got), end-offset, offset)
+			}
+		})
+	}
+}
+
+func TestEncryptedMultipartNeedsPartsInOrder(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newEncrypted(t)
+
+	uploadID, err := store.CreateMultipartUpload(ctx, "a.mp3", "audio/mpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []CompletedPart
+	for _, number := range []int{2, 1} {
+		part, err := store.UploadPart(ctx, "a.mp3", uploadID, number, strings.NewReader("abc"), 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, *part)
+	}
+	if _, err := store.CompleteMultipartUpload(ctx, "a.mp3", uploadID, parts); err == nil {
+		t.Fatal("completed an upload with parts out of order")
+	}
+}
+
+func TestEncryptedCopy(t *testing.T) {
+	ctx := context.Background()
+	store, inner := newEncrypted(t)
+	data := plaintext(chunk + 10)
+	if _, err := store.Upload(ctx, bytes.NewReader(data), "incoming/a.mp3", "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Copy(ctx, "incoming/a.mp3", "a.mp3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "incoming/a.mp3"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readRange(t, store, "a.mp3", chunk-2, 4); !bytes.Equal(got, data[chunk-2:chunk+2]) {
+		t.Fatal("copy did not read back")
+	}
+
+	// The wrapped key is bound to its object, so an envelope moved by hand is refused
+	if err := inner.Copy(ctx, "a.mp3", "b.mp3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := inner.Copy(ctx, "a.mp3"+envelopeSuffix, "b.mp3"+envelopeSuffix); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Open(ctx, "b.mp3", 0, -1); err == nil {
+		t.Fatal("opened an object with another object's envelope")
+	}
+}
+
+func TestEncryptedReadsObjectsStoredBeforeEncryption(t *testing.T) {
+	ctx := context.Background()
+	store, inner := newEncrypted(t)
+	if _, err := inner.Upload(ctx, strings.NewReader("plain audio"), "old.mp3", "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readRange(t, store, "old.mp3", 6, -1); string(got) != "audio" {
+		t.Fatalf("read %q", got)
+	}
+	if err := store.Copy(ctx, "old.mp3", "copy.mp3"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, store, "copy.mp3"); got != "plain audio" {
+		t.Fatalf("read %q from the copy", got)
+	}
+}
+
+func TestEncryptedDetectsTampering(t *testing.T) {
+	ctx := context.Background()
+	store, inner := newEncrypted(t)
+	if _, err := store.Upload(ctx, bytes.NewReader(plaintext(2*chunk)), "a.mp3", "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := readRange(t, inner, "a.mp3", 0, -1)
+	stored[partSaltSize+chunk+gcmTagSize+3] ^= 1 // In the second chunk
+	if _, err := inner.Upload(ctx, bytes.NewReader(stored), "a.mp3", "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first chunk is still intact
+	if got := readRange(t, store, "a.mp3", 0, chunk); len(got) != chunk {
+		t.Fatalf("read %d bytes of the untouched chunk", len(got))
+	}
+	body, err := store.Open(ctx, "a.mp3", chunk, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if _, err := io.ReadAll(body); err == nil {
+		t.Fatal("read a modified chunk without an error")
+	}
+}
+
+func TestEncryptedRotateKeys(t *testing.T) {
+	ctx := context.Background()
+	inner := newLocal(t)
+	oldKey, newKeyEntry := newKey(t, "old"), newKey(t, "new")
+
+	before := NewEncryptedProvider(inner, newKeyring(t, oldKey))
+	single, multi := plaintext(chunk+3), plaintext(2*chunk+9)
+	if _, err := before.Upload(ctx, bytes.NewReader(single), "a.mp3", "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+	uploadParts(t, before, "b.mp3", multi, []int{chunk + 1, chunk + 8})
+	pending, err := before.CreateMultipartUpload(ctx, "c.mp3", "audio/mpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new key goes first, the old one stays until rotation is done
+	during := NewEncryptedProvider(inner, newKeyring(t, newKeyEntry, oldKey))
+	report, err := during.RotateKeys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *report != (RotationReport{Scanned: 3, Rewrapped: 2}) {
+		t.Fatalf("first rotation = %+v, want 3 scanned and 2 re-wrapped", *report)
+	}
+	report, err = during.RotateKeys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Rewrapped != 0 || report.Failed != 0 {
+		t.Fatalf("second rotation = %+v, want nothing left to do", *report)
+	}
+
+	// The pending upload moves to the active key as it completes
+	part, err := during.UploadPart(ctx, "c.mp3", pending, 1, strings.NewReader("tail"), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := during.CompleteMultipartUpload(ctx, "c.mp3", pending, []CompletedPart{*part}); err != nil {
+		t.Fatal(err)
+	}
+
+	// With the old key retired everything still reads
+	after := NewEncryptedProvider(inner, newKeyring(t, newKeyEntry))
+	if got := readRange(t, after, "a.mp3", 0, -1); !bytes.Equal(got, single) {
+		t.Fatal("single-part object unreadable after retiring the old key")
+	}
+	if got := readRange(t, after, "b.mp3", chunk, chunk+2); !bytes.Equal(got, multi[chunk:2*chunk+2]) {
+		t.Fatal("multipart object unreadable after retiring the old key")
+	}
+	if got := readAll(t, after, "c.mp3"); got != "tail" {
+		t.Fatalf("read %q from the upload completed during rotation", got)
+	}
+
+	// An object rotation never saw can't be read without its key
+	if _, err := before.Upload(ctx, strings.NewReader("late"), "d.mp3", "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := after.Open(ctx, "d.mp3", 0, -1); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Open of an unrotated object = %v, want ErrUnknownKey", err)
+	}
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrPrivateAddress is returned for connections to addresses on the server's
+// own network, which user-supplied bucket endpoints must not reach
+var ErrPrivateAddress = errors.New("address is not publicly routable")
+
+// Ranges that IsPublicAddress rejects beyond what netip classifies
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which can reach private IPv4
+}
+
+// IsPublicAddress reports whether ip is a unicast address outside the
+// private, loopback and link-local ranges
+func IsPublicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// rejectPrivateDial is a net.Dialer Control that refuses non-public
+// addresses. It runs after name resolution, so a host that changes its DNS
+// answer after being checked is still caught.
+func rejectPrivateDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || !IsPublicAddress(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"52.216.8.1", true},
+		{"2600:1f18::1", true},
+		{"127.0.0.1", false},
+		{"10.0.0.5", false},
+		{"172.16.3.4", false},
+		{"192.168.1.10", false},
+		{"169.254.169.254", false}, // Cloud metadata
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := IsPublicAddress(netip.MustParseAddr(tt.addr)); got != tt.public {
+				t.Fatalf("IsPublicAddress(%s) = %v, want %v", tt.addr, got, tt.public)
+			}
+		})
+	}
+}
+
+func TestRejectPrivateDial(t *testing.T) {
+	if err := rejectPrivateDial("tcp", "52.216.8.1:443", nil); err != nil {
+		t.Fatalf("public address rejected: %v", err)
+	}
+	for _, address := range []string{"127.0.0.1:6379", "[::1]:5432", "169.254.169.254:80"} {
+		if err := rejectPrivateDial("tcp", address, nil); !errors.Is(err, ErrPrivateAddress) {
+			t.Fatalf("dial to %s returned %v, want %v", address, err, ErrPrivateAddress)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jaykapade/meeting-assistant/backend/internal/config"
+)
+
+// NewProvider builds the provider selected by STORAGE_DRIVER, so infrastructure
+// can be swapped just by changing an env var. Configured replicas wrap it in
+// a MirroredProvider, encryption keys in an EncryptedProvider on top, and a
+// cold tier in a TieredProvider above that.
+func NewProvider(ctx context.Context, cfg config.StorageConfig) (Provider, error) {
+	store, err := newBackend(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replicas sit below encryption so they only ever receive ciphertext
+	if len(cfg.Replicas) > 0 {
+		replicas := make([]NamedProvider, len(cfg.Replicas))
+		for i, replica := range cfg.Replicas {
+			replicaStore, err := newSecondary(ctx, replica)
+			if err != nil {
+				return nil, fmt.Errorf("failed to initialize replica %s: %w", replica.Name, err)
+			}
+			replicas[i] = NamedProvider{Name: replica.Name, Store: replicaStore}
+		}
+
+		store, err = NewMirroredProvider(store, replicas, ReplicationMode(cfg.ReplicationMode))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var keys *Keyring
+	if cfg.EncryptionKeys != "" || cfg.EncryptionKeyFile != "" {
+		keys, err = LoadKeyring(cfg.EncryptionKeys, cfg.EncryptionKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load encryption keys: %w", err)
+		}
+		store = NewEncryptedProvider(store, keys)
+	}
+
+	// Each tier is encrypted on its own, so objects are re-sealed as they move
+	if cfg.Cold != nil {
+		cold, err := newSecondary(ctx, *cfg.Cold)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize cold tier: %w", err)
+		}
+		if keys != nil {
+			cold = NewEncryptedProvider(cold, keys)
+		}
+		store = NewTieredProvider(store, cold)
+	}
+	return store, nil
+}
+
+// newSecondary connects to a replica or the cold tier
+func newSecondary(ctx context.Context, cfg config.ReplicaConfig) (Provider, error) {
+	store, err := newBackend(ctx, config.StorageConfig{
+		Driver:    cfg.Driver,
+		Bucket:    cfg.Bucket,
+		Endpoint:  cfg.Endpoint,
+		Region:    cfg.Region,
+		AccessKey: cfg.AccessKey,
+		SecretKey: cfg.SecretKey,
+		RootDir:   cfg.RootDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s3Store, ok := store.(*S3Provider); ok && cfg.StorageClass != "" {
+		s3Store.SetStorageClass(cfg.StorageClass)
+	}
+	return store, nil
+}
+
+// newBackend connects to a single store
+func newBackend(ctx context.Context, cfg config.StorageConfig) (Provider, error) {
+	var store Provider
+	var err error
+
+	switch cfg.Driver {
+	case "minio", "s3":
+		store, err = NewS3Provider(
+			ctx,
+			cfg.Bucket,
+			cfg.Endpoint,
+			cfg.Region,
+			cfg.AccessKey,
+			cfg.SecretKey,
+		)
+	case "local":
+		store, err = NewLocalDiskProvider(
+			cfg.RootDir,
+			cfg.PublicURL,
+			cfg.SigningSecret,
+		)
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", cfg.Driver)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	if s3Store, ok := store.(*S3Provider); ok && cfg.PublicEndpointOnly {
+		s3Store.RestrictToPublicAddresses()
+	}
+	return store, nil
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var ErrUnknownKey = errors.New("key-encryption key not found")
+
+// Keyring holds the key-encryption keys (KEKs) that wrap per-object data keys.
+// The active key wraps new data keys; the others only unwrap older ones until
+// they have been rotated.
+type Keyring struct {
+	active string
+	keys   map[string]cipher.AEAD
+}
+
+// ParseKeyring reads "id:base64" entries separated by commas or newlines.
+// Each key must decode to 32 bytes (AES-256). The first entry is active.
+func ParseKeyring(spec string) (*Keyring, error) {
+	ring := &Keyring{keys: make(map[string]cipher.AEAD)}
+
+	entries := strings.FieldsFunc(spec, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid key entry %q, expected id:base64", entry)
+		}
+		if _, exists := ring.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", id)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes of base64", id)
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+
+		ring.keys[id] = aead
+		if ring.active == "" {
+			ring.active = id
+		}
+	}
+
+	if ring.active == "" {
+		return nil, errors.New("no encryption keys configured")
+	}
+	return ring, nil
+}
+
+// LoadKeyring combines keys from config with keys from a file. Keys listed
+// inline come first, so the active key can be rotated without editing the file.
+func LoadKeyring(spec, path string) (*Keyring, error) {
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		spec = strings.Join([]string{spec, string(raw)}, "\n")
+	}
+	return ParseKeyring(spec)
+}
+
+// ActiveID is the key that wraps new data keys
+func (k *Keyring) ActiveID() string {
+	return k.active
+}
+
+// Wrap encrypts a data key with the active KEK, binding it to aad
+func (k *Keyring) Wrap(dataKey, aad []byte) (string, []byte, error) {
+	aead := k.keys[k.active]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return k.active, aead.Seal(nonce, nonce, dataKey, aad), nil
+}
+
+// Unwrap decrypts a data key wrapped by the KEK with the given id
+func (k *Keyring) Unwrap(id string, wrapped, aad []byte) ([]byte, error) {
+	aead, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped key is truncated")
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, sealed, aad)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,425 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidKey is returned when a key would escape the storage root
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// ErrInvalidSignature is returned when a signed URL is tampered with or expired
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
+// LocalDownloadPath is the route prefix that serves signed local downloads and uploads
+const LocalDownloadPath = "/api/v1/file/local/"
+
+type LocalDiskProvider struct {
+	root    string
+	baseURL string
+	secret  []byte
+}
+
+// Ensure LocalDiskProvider satisfies the interface at compile time
+var _ Provider = (*LocalDiskProvider)(nil)
+
+func NewLocalDiskProvider(root, baseURL, secret string) (*LocalDiskProvider, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage root: %w", err)
+	}
+
+	if err := os.MkdirAll(absRoot, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage root: %w", err)
+	}
+
+	// Without a configured secret, links are only valid until the server restarts
+	key := []byte(secret)
+	if len(key) == 0 {
+		log.Println("No STORAGE_SIGNING_SECRET set, generating an ephemeral one")
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate signing secret: %w", err)
+		}
+	}
+
+	log.Printf("Local storage provider initialized at: %s", absRoot)
+	return &LocalDiskProvider{
+		root:    absRoot,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secret:  key,
+	}, nil
+}
+
+func (p *LocalDiskProvider) Upload(ctx context.Context, file io.Reader, key string, contentType string) (*UploadResult, error) {
+	path, err := p.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Write to a temp file in the same directory so the rename is atomic
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	size, err := io.Copy(tmp, &contextReader{ctx: ctx, r: file})
+	if err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to sync file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to move file into place: %w", err)
+	}
+
+	log.Printf("Successfully stored file: %s", key)
+
+	return &UploadResult{
+		Key:      key,
+		Size:     size,
+		MimeType: contentType,
+	}, nil
+}
+
+func (p *LocalDiskProvider) Delete(ctx context.Context, key string) error {
+	path, err := p.resolve(key)
+	if err != nil {
+		return err
+	}
+
+	// Deleting a missing object is not an error, same as S3
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+func (p *LocalDiskProvider) Copy(ctx context.Context, srcKey string, dstKey string) error {
+	src, err := p.OpenFile(srcKey)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer src.Close()
+
+	_, err = p.Upload(ctx, src, dstKey, "")
+	return err
+}
+
+func (p *LocalDiskProvider) GetSignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error) {
+	if _, err := p.resolve(key); err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(time.Now().Add(opts.ttl()).Unix(), 10))
+	if opts.Filename != "" {
+		query.Set("filename", opts.Filename)
+	}
+	if opts.ContentType != "" {
+		query.Set("content_type", opts.ContentType)
+	}
+	query.Set("signature", p.sign(http.MethodGet, key, query))
+
+	return p.baseURL + LocalDownloadPath + escapeKey(key) + "?" + query.Encode(), nil
+}
+
+func (p *LocalDiskProvider) GetSignedUploadURL(ctx context.Context, key string, contentType string, size int64, ttl time.Duration) (*SignedUpload, error) {
+	if _, err := p.resolve(key); err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(time.Now().Add(ttl).Unix(), 10))
+	query.Set("content_type", contentType)
+	query.Set("size", strconv.FormatInt(size, 10))
+	query.Set("signature", p.sign(http.MethodPut, key, query))
+
+	return &SignedUpload{
+		URL:     p.baseURL + LocalDownloadPath + escapeKey(key) + "?" + query.Encode(),
+		Method:  http.MethodPut,
+		Headers: map[string]string{"Content-Type": contentType},
+	}, nil
+}
+
+func (p *LocalDiskProvider) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	path, err := p.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// Local files don't keep a content type, so derive it from the extension
+	return &ObjectInfo{
+		Key:          key,
+		Size:         info.Size(),
+		ContentType:  mime.TypeByExtension(filepath.Ext(path)),
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+func (p *LocalDiskProvider) Open(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	file, err := p.OpenFile(key)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek file: %w", err)
+	}
+	if length < 0 {
+		return file, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, length), file}, nil
+}
+
+// List walks root in lexical order, like S3 does, skipping temp files and multipart staging
+func (p *LocalDiskProvider) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	err := filepath.WalkDir(p.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if path == p.root {
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(p.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if entry.IsDir() {
+			// Only descend into directories that can still contain a match
+			if !strings.HasPrefix(key+"/", prefix) && !strings.HasPrefix(prefix, key+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return fn(ObjectInfo{
+			Key:          key,
+			Size:         info.Size(),
+			ContentType:  mime.TypeByExtension(filepath.Ext(path)),
+			LastModified: info.ModTime(),
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+	return nil
+}
+
+// OpenFile returns the file stored under key
+func (p *LocalDiskProvider) OpenFile(key string) (*os.File, error) {
+	path, err := p.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Verify checks that a request to the local file route was signed by this provider
+func (p *LocalDiskProvider) Verify(method, key string, query url.Values) error {
+	exp, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(p.sign(method, key, query))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// sign covers the method, key, expiry and every constraint so none can be altered
+func (p *LocalDiskProvider) sign(method, key string, query url.Values) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(strings.Join([]string{
+		method,
+		key,
+		query.Get("expires"),
+		query.Get("filename"),
+		query.Get("content_type"),
+		query.Get("size"),
+	}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// resolve maps a key to a path inside root, rejecting anything that escapes it
+func (p *LocalDiskProvider) resolve(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, "\\\x00") || strings.HasPrefix(key, "/") {
+		return "", ErrInvalidKey
+	}
+	// Dot-prefixed names are reserved for temp files and multipart staging
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || strings.HasPrefix(part, ".") {
+			return "", ErrInvalidKey
+		}
+	}
+
+	path := filepath.Join(p.root, filepath.FromSlash(key))
+	rel, err := filepath.Rel(p.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", ErrInvalidKey
+	}
+	return path, nil
+}
+
+func (p *LocalDiskProvider) CreateMultipartUpload(ctx context.Context, key string, contentType string) (string, error) {
+	if _, err := p.resolve(key); err != nil {
+		return "", err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate upload id: %w", err)
+	}
+	uploadID := hex.EncodeToString(id)
+
+	if err := os.MkdirAll(p.stagingDir(uploadID), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	return uploadID, nil
+}
+
+func (p *LocalDiskProvider) UploadPart(ctx context.Context, key string, uploadID string, number int, part io.ReadSeeker, size int64) (*CompletedPart, error) {
+	if !isHex(uploadID) {
+		return nil, ErrInvalidKey
+	}
+
+	partPath := filepath.Join(p.stagingDir(uploadID), strconv.Itoa(number))
+	file, err := os.Create(partPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create part %d: %w", number, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(file, hash), &contextReader{ctx: ctx, r: part})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write part %d: %w", number, err)
+	}
+	if written != size {
+		return nil, fmt.Errorf("part %d is %d bytes, expected %d", number, written, size)
+	}
+
+	return &CompletedPart{Number: number, ETag: hex.EncodeToString(hash.Sum(nil)), Size: written}, nil
+}
+
+func (p *LocalDiskProvider) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []CompletedPart) (*UploadResult, error) {
+	if !isHex(uploadID) {
+		return nil, ErrInvalidKey
+	}
+
+	// Concatenate the parts in order and reuse Upload for the atomic rename
+	readers := make([]io.Reader, 0, len(parts))
+	for _, part := range parts {
+		file, err := os.Open(filepath.Join(p.stagingDir(uploadID), strconv.Itoa(part.Number)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open part %d: %w", part.Number, err)
+		}
+		defer file.Close()
+		readers = append(readers, file)
+	}
+
+	result, err := p.Upload(ctx, io.MultiReader(readers...), key, "")
+	if err != nil {
+		return nil, err
+	}
+
+	os.RemoveAll(p.stagingDir(uploadID))
+	return result, nil
+}
+
+func (p *LocalDiskProvider) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	if !isHex(uploadID) {
+		return ErrInvalidKey
+	}
+	return os.RemoveAll(p.stagingDir(uploadID))
+}
+
+func (p *LocalDiskProvider) stagingDir(uploadID string) string {
+	return filepath.Join(p.root, ".multipart", uploadID)
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return s != "" && err == nil
+}
+
+func escapeKey(key string) string {
+	parts := strings.Split(key, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// contextReader stops a copy as soon as the context is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(b []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(b)
+}
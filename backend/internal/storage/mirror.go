@@ -0,0 +1,430 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+type ReplicationMode string
+
+const (
+	// ReplicateSync copies to every replica before a write returns
+	ReplicateSync ReplicationMode = "sync"
+	// ReplicateAsync queues the copy and returns once the primary has the object
+	ReplicateAsync ReplicationMode = "async"
+)
+
+const (
+	// Keys waiting per replica before new writes are left for repair
+	replicationQueueSize = 1024
+	replicationAttempts  = 3
+	replicationTimeout   = 5 * time.Minute
+)
+
+// ReplicaStatus reports how far a replica is behind the primary
+type ReplicaStatus struct {
+	Name             string     `json:"name"`
+	Pending          int        `json:"pending"`
+	Replicated       int64      `json:"replicated"`
+	Failed           int64      `json:"failed"`
+	LastLagSeconds   float64    `json:"last_lag_seconds"` // From the primary write to the replica write
+	MaxLagSeconds    float64    `json:"max_lag_seconds"`
+	OldestPendingAt  *time.Time `json:"oldest_pending_at,omitempty"`
+	LastReplicatedAt *time.Time `json:"last_replicated_at,omitempty"`
+	LastError        string     `json:"last_error,omitempty"`
+	LastErrorKey     string     `json:"last_error_key,omitempty"`
+	LastErrorAt      *time.Time `json:"last_error_at,omitempty"`
+}
+
+type replicationTask struct {
+	key     string
+	written time.Time
+}
+
+type replica struct {
+	name  string
+	store Provider
+	tasks chan replicationTask
+
+	mu      sync.Mutex
+	status  ReplicaStatus
+	pending map[string]time.Time
+}
+
+// MirroredProvider keeps a copy of every object on one or more replicas.
+// Writes go to the primary and are then replayed on each replica by reading
+// the object back, so replicas converge on the primary no matter the order
+// of writes and deletes. Reads use the primary and fall back to a replica.
+//
+// Presigned uploads go straight to the primary and reach replicas on the
+// next repair, as do async writes lost to a restart or a full queue.
+type MirroredProvider struct {
+	primary  Provider
+	replicas []*replica
+	mode     ReplicationMode
+}
+
+var _ Provider = (*MirroredProvider)(nil)
+
+type primaryOnlyKey struct{}
+
+// primaryOnly marks calls made with the context as only concerning the
+// primary: reads don't fall back to replicas and deletes aren't replayed on
+// them. Moving an object to the cold tier uses it, so replicas keep their
+// copy of archived objects and can't be mistaken for the hot copy.
+func primaryOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryOnlyKey{}, true)
+}
+
+func isPrimaryOnly(ctx context.Context) bool {
+	return ctx.Value(primaryOnlyKey{}) != nil
+}
+
+// NamedProvider pairs a replica with the name it is reported under
+type NamedProvider struct {
+	Name  string
+	Store Provider
+}
+
+func NewMirroredProvider(primary Provider, replicas []NamedProvider, mode ReplicationMode) (*MirroredProvider, error) {
+	if mode != ReplicateSync && mode != ReplicateAsync {
+		return nil, fmt.Errorf("unknown replication mode: %s", mode)
+	}
+
+	p := &MirroredProvider{primary: primary, mode: mode}
+	for _, named := range replicas {
+		r := &replica{
+			name:    named.Name,
+			store:   named.Store,
+			status:  ReplicaStatus{Name: named.Name},
+			pending: make(map[string]time.Time),
+		}
+		if mode == ReplicateAsync {
+			r.tasks = make(chan replicationTask, replicationQueueSize)
+			go p.worker(r)
+		}
+		p.replicas = append(p.replicas, r)
+	}
+
+	log.Printf("Mirrored storage initialized with %d replicas (%s)", len(p.replicas), mode)
+	return p, nil
+}
+
+// Status reports replication lag and failures for every replica
+func (p *MirroredProvider) Status() []ReplicaStatus {
+	statuses := make([]ReplicaStatus, len(p.replicas))
+	for i, r := range p.replicas {
+		r.mu.Lock()
+		status := r.status
+		status.Pending = len(r.pending)
+		for _, written := range r.pending {
+			if status.OldestPendingAt == nil || written.Before(*status.OldestPendingAt) {
+				at := written
+				status.OldestPendingAt = &at
+			}
+		}
+		r.mu.Unlock()
+		statuses[i] = status
+	}
+	return statuses
+}
+
+func (p *MirroredProvider) Upload(ctx context.Context, file io.Reader, key string, contentType string) (*UploadResult, error) {
+	result, err := p.primary.Upload(ctx, file, key, contentType)
+	if err != nil {
+		return nil, err
+	}
+	p.replicate(ctx, key)
+	return result, nil
+}
+
+func (p *MirroredProvider) Delete(ctx context.Context, key string) error {
+	if err := p.primary.Delete(ctx, key); err != nil {
+		return err
+	}
+	if isPrimaryOnly(ctx) {
+		return nil
+	}
+	p.replicate(ctx, key)
+	return nil
+}
+
+func (p *MirroredProvider) Copy(ctx context.Context, srcKey string, dstKey string) error {
+	if err := p.primary.Copy(ctx, srcKey, dstKey); err != nil {
+		return err
+	}
+	p.replicate(ctx, dstKey)
+	return nil
+}
+
+func (p *MirroredProvider) GetSignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error) {
+	return p.primary.GetSignedURL(ctx, key, opts)
+}
+
+func (p *MirroredProvider) GetSignedUploadURL(ctx context.Context, key string, contentType string, size int64, ttl time.Duration) (*SignedUpload, error) {
+	return p.primary.GetSignedUploadURL(ctx, key, contentType, size, ttl)
+}
+
+// Stat falls back to the replicas, so a recording lost on the primary is still found
+func (p *MirroredProvider) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := p.primary.Stat(ctx, key)
+	if err == nil || isPrimaryOnly(ctx) {
+		return info, err
+	}
+	for _, r := range p.replicas {
+		if info, replicaErr := r.store.Stat(ctx, key); replicaErr == nil {
+			log.Printf("Primary stat of %s failed (%v), served by replica %s", key, err, r.name)
+			return info, nil
+		}
+	}
+	return nil, err
+}
+
+func (p *MirroredProvider) Open(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	body, err := p.primary.Open(ctx, key, offset, length)
+	if err == nil || isPrimaryOnly(ctx) {
+		return body, err
+	}
+	for _, r := range p.replicas {
+		if body, replicaErr := r.store.Open(ctx, key, offset, length); replicaErr == nil {
+			log.Printf("Primary read of %s failed (%v), served by replica %s", key, err, r.name)
+			return body, nil
+		}
+	}
+	return nil, err
+}
+
+func (p *MirroredProvider) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	return p.primary.List(ctx, prefix, fn)
+}
+
+// Multipart uploads are assembled on the primary and replicated once complete
+func (p *MirroredProvider) CreateMultipartUpload(ctx context.Context, key string, contentType string) (string, error) {
+	return p.primary.CreateMultipartUpload(ctx, key, contentType)
+}
+
+func (p *MirroredProvider) UploadPart(ctx context.Context, key string, uploadID string, number int, part io.ReadSeeker, size int64) (*CompletedPart, error) {
+	return p.primary.UploadPart(ctx, key, uploadID, number, part, size)
+}
+
+func (p *MirroredProvider) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []CompletedPart) (*UploadResult, error) {
+	result, err := p.primary.CompleteMultipartUpload(ctx, key, uploadID, parts)
+	if err != nil {
+		return nil, err
+	}
+	p.replicate(ctx, key)
+	return result, nil
+}
+
+func (p *MirroredProvider) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	return p.primary.AbortMultipartUpload(ctx, key, uploadID)
+}
+
+// RepairReport summarises a Repair run
+type RepairReport struct {
+	DryRun   bool           `json:"dry_run"`
+	Scanned  int            `json:"scanned"`
+	Missing  map[string]int `json:"missing"` // Per replica
+	Repaired int            `json:"repaired"`
+	Failed   int            `json:"failed"`
+}
+
+// Repair copies every primary object that a replica is missing or holds at a
+// different size. Replicas are only backfilled, never pruned.
+func (p *MirroredProvider) Repair(ctx context.Context, prefix string, dryRun bool) (*RepairReport, error) {
+	report := &RepairReport{DryRun: dryRun, Missing: make(map[string]int)}
+	for _, r := range p.replicas {
+		report.Missing[r.name] = 0
+	}
+
+	err := p.primary.List(ctx, prefix, func(object ObjectInfo) error {
+		report.Scanned++
+		for _, r := range p.replicas {
+			info, err := r.store.Stat(ctx, object.Key)
+			if err == nil && info.Size == object.Size {
+				continue
+			}
+			if err != nil && !errors.Is(err, ErrObjectNotFound) {
+				log.Printf("Failed to check %s on replica %s: %v", object.Key, r.name, err)
+			}
+
+			report.Missing[r.name]++
+			if dryRun {
+				continue
+			}
+			if err := p.sync(ctx, r, object.Key, object.LastModified); err != nil {
+				report.Failed++
+				continue
+			}
+			report.Repaired++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// replicate brings every replica in line with the primary for key
+func (p *MirroredProvider) replicate(ctx context.Context, key string) {
+	written := time.Now()
+	for _, r := range p.replicas {
+		if p.mode == ReplicateSync {
+			p.sync(context.WithoutCancel(ctx), r, key, written)
+			continue
+		}
+
+		r.mu.Lock()
+		if _, queued := r.pending[key]; queued {
+			// The queued task reads whatever the primary holds when it runs
+			r.mu.Unlock()
+			continue
+		}
+		r.pending[key] = written
+		r.mu.Unlock()
+
+		select {
+		case r.tasks <- replicationTask{key: key, written: written}:
+		default:
+			r.mu.Lock()
+			delete(r.pending, key)
+			r.mu.Unlock()
+			r.record(key, written, errors.New("replication queue is full"))
+		}
+	}
+}
+
+func (p *MirroredProvider) worker(r *replica) {
+	for task := range r.tasks {
+		// Let writes that arrive while this one runs queue up again
+		r.mu.Lock()
+		delete(r.pending, task.key)
+		r.mu.Unlock()
+
+		var err error
+		for attempt := 1; attempt <= replicationAttempts; attempt++ {
+			if attempt > 1 {
+				time.Sleep(time.Duration(attempt-1) * time.Second)
+			}
+			if err = p.copyToReplica(context.Background(), r, task.key); err == nil {
+				break
+			}
+		}
+		r.record(task.key, task.written, err)
+	}
+}
+
+// sync replicates key to one replica straight away and records the outcome
+func (p *MirroredProvider) sync(ctx context.Context, r *replica, key string, written time.Time) error {
+	err := p.copyToReplica(ctx, r, key)
+	r.record(key, written, err)
+	return err
+}
+
+// copyToReplica copies the primary's object to a replica, or deletes the
+// replica's copy if the primary no longer has it
+func (p *MirroredProvider) copyToReplica(ctx context.Context, r *replica, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, replicationTimeout)
+	defer cancel()
+
+	info, err := p.primary.Stat(ctx, key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return r.store.Delete(ctx, key)
+	}
+	if err != nil {
+		return err
+	}
+
+	body, err := p.primary.Open(ctx, key, 0, -1)
+	if errors.Is(err, ErrObjectNotFound) {
+		return r.store.Delete(ctx, key)
+	}
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	_, err = r.store.Upload(ctx, body, key, info.ContentType)
+	return err
+}
+
+// record updates the replica's status after a replication attempt
+func (r *replica) record(key string, written time.Time, err error) {
+	now := time.Now()
+	if err != nil {
+		log.Printf("Replication of %s to %s failed: %v", key, r.name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.status.Failed++
+		r.status.LastError = err.Error()
+		r.status.LastErrorKey = key
+		r.status.LastErrorAt = &now
+		return
+	}
+
+	lag := now.Sub(written).Seconds()
+	r.status.Replicated++
+	r.status.LastReplicatedAt = &now
+	r.status.LastLagSeconds = lag
+	r.status.MaxLagSeconds = max(r.status.MaxLagSeconds, lag)
+}
+
+// SigningProvider returns the default provider behind any router, tiers or
+// mirrors, which is the one that signed URLs point at
+func SigningProvider(store Provider) Provider {
+	for {
+		switch p := store.(type) {
+		case *Router:
+			store = p.fallback
+		case *TieredProvider:
+			store = p.hot
+		case *MirroredProvider:
+			store = p.primary
+		default:
+			return store
+		}
+	}
+}
+
+// FindMirror returns the mirror inside a chain of wrapping providers, if any
+func FindMirror(store Provider) *MirroredProvider {
+	mirror, _ := findProvider[*MirroredProvider](store)
+	return mirror
+}
+
+// FindTiered returns the tiered provider inside a chain of wrapping providers, if any
+func FindTiered(store Provider) *TieredProvider {
+	tiered, _ := findProvider[*TieredProvider](store)
+	return tiered
+}
+
+// FindEncrypted returns the encryption layer inside a chain of wrapping providers, if any
+func FindEncrypted(store Provider) *EncryptedProvider {
+	encrypted, _ := findProvider[*EncryptedProvider](store)
+	return encrypted
+}
+
+// findProvider walks the Unwrap chain from store to the first provider of type T
+func findProvider[T Provider](store Provider) (T, bool) {
+	for store != nil {
+		if found, ok := store.(T); ok {
+			return found, true
+		}
+		wrapper, ok := store.(interface{ Unwrap() Provider })
+		if !ok {
+			break
+		}
+		store = wrapper.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newMirror(t *testing.T, mode ReplicationMode, replicas ...NamedProvider) *MirroredProvider {
+	t.Helper()
+	mirror, err := NewMirroredProvider(newLocal(t), replicas, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mirror
+}
+
+// waitFor polls until cond holds, for the async replication worker
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// failingProvider refuses every write, like a replica that is down
+type failingProvider struct {
+	*LocalDiskProvider
+}
+
+func (p failingProvider) Upload(ctx context.Context, file io.Reader, key string, contentType string) (*UploadResult, error) {
+	return nil, errors.New("replica is down")
+}
+
+func TestMirrorSyncReplicatesWrites(t *testing.T) {
+	ctx := context.Background()
+	replica := newLocal(t)
+	mirror := newMirror(t, ReplicateSync, NamedProvider{Name: "dr", Store: replica})
+
+	if _, err := mirror.Upload(ctx, strings.NewReader("audio"), "incoming/a.mp3", "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, replica, "incoming/a.mp3"); got != "audio" {
+		t.Fatalf("replica holds %q after upload", got)
+	}
+
+	if err := mirror.Copy(ctx, "incoming/a.mp3", "a.mp3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mirror.Delete(ctx, "incoming/a.mp3"); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(t, replica, "a.mp3") || exists(t, replica, "incoming/a.mp3") {
+		t.Fatal("replica did not follow the copy and delete")
+	}
+
+	uploadID, err := mirror.CreateMultipartUpload(ctx, "b.mp3", "audio/mpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part, err := mirror.UploadPart(ctx, "b.mp3", uploadID, 1, strings.NewReader("parts"), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists(t, replica, "b.mp3") {
+		t.Fatal("replica got a multipart upload before it completed")
+	}
+	if _, err := mirror.CompleteMultipartUpload(ctx, "b.mp3", uploadID, []CompletedPart{*part}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, replica, "b.mp3"); got != "parts" {
+		t.Fatalf("replica holds %q after multipart upload", got)
+	}
+
+	status := mirror.Status()
+	if len(status) != 1 || status[0].Name != "dr" || status[0].Replicated != 4 || status[0].Failed != 0 {
+		t.Fatalf("Status = %+v, want 4 replicated to dr", status)
+	}
+}
+
+func TestMirrorAsyncReplicatesWrites(t *testing.T) {
+	ctx := context.Background()
+	replica := newLocal(t)
+	mirror := newMirror(t, ReplicateAsync, NamedProvider{Name: "dr", Store: replica})
+
+	if _, err := mirror.Upload(ctx, strings.NewReader("audio"), "a.mp3", "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the upload to replicate", func() bool {
+		_, err := replica.Stat(ctx, "a.mp3")
+		return err == nil
+	})
+
+	if err := mirror.Delete(ctx, "a.mp3"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the delete to replicate", func() bool {
+		_, err := replica.Stat(ctx, "a.mp3")
+		return errors.Is(err, ErrObjectNotFound)
+	})
+
+	waitFor(t, "the status to settle", func() bool {
+		status := mirror.Status()[0]
+		return status.Replicated == 2 && status.Pending == 0
+	})
+	if status := mirror.Status()[0]; status.LastReplicatedAt == nil || status.OldestPendingAt != nil {
+		t.Fatalf("Status = %+v", status)
+	}
+}
+
+func TestMirrorRecordsReplicaFailures(t *testing.T) {
+	ctx := context.Background()
+	mirror := newMirror(t, ReplicateSync, NamedProvider{Name: "down", Store: failingProvider{newLocal(t)}})
+
+	// The primary has the object, so the write still succeeds
+	if _, err := mirror.Upload(ctx, strings.NewReader("audio"), "a.mp3", "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, mirror, "a.mp3"); got != "audio" {
+		t.Fatalf("read %q", got)
+	}
+
+	status := mirror.Status()[0]
+	if status.Failed != 1 || status.Replicated != 0 || status.LastErrorKey != "a.mp3" || status.LastError == "" {
+		t.Fatalf("Status = %+v, want one failure on a.mp3", status)
+	}
+}
+
+func TestMirrorReadsFallBackToReplica(t *testing.T) {
+	ctx := context.Background()
+	primary, replica := newLocal(t), newLocal(t)
+	mirror, err := NewMirroredProvider(primary, []NamedProvider{{Name: "dr", Store: replica}}, ReplicateSync)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mirror.Upload(ctx, strings.NewReader("audio"), "a.mp3", "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.Delete(ctx, "a.mp3"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readAll(t, mirror, "a.mp3"); got != "audio" {
+		t.Fatalf("read %q, want the replica's copy", got)
+	}
+	if _, err := mirror.Stat(ctx, "a.mp3"); err != nil {
+		t.Fatalf("Stat = %v, want the replica's copy", err)
+	}
+
+	// Callers that only care about the primary see it is gone
+	if _, err := mirror.Stat(primaryOnly(ctx), "a.mp3"); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("primary-only Stat = %v, want ErrObjectNotFound", err)
+	}
+	if _, err := mirror.Open(primaryOnly(ctx), "a.mp3", 0, -1); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("primary-only Open = %v, want ErrObjectNotFound", err)
+	}
+	if _, err := mirror.Open(ctx, "missing.mp3", 0, -1); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("Open of an object nowhere = %v, want ErrObjectNotFound", err)
+	}
+}
+
+func TestMirrorRepair(t *testing.T) {
+	ctx := context.Background()
+	primary, first, second := newLocal(t), newLocal(t), newLocal(t)
+	mirror, err := NewMirroredProvider(primary, []NamedProvider{
+		{Name: "first", Store: first},
+		{Name: "second", Store: second},
+	}, ReplicateSync)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Written straight to the primary, like a presigned upload
+	for _, key := range []string{"rec/a.mp3", "rec/b.mp3", "other/c.mp3"} {
+		if _, err := primary.Upload(ctx, strings.NewReader("audio "+key), key, "audio/mpeg"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// first already has a, but truncated; second has an object the primary lacks
+	if _, err := first.Upload(ctx, strings.NewReader("aud"), "rec/a.mp3", "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Upload(ctx, strings.NewReader("stray"), "rec/stray.mp3", "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := mirror.Repair(ctx, "rec/", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scanned != 2 || report.Missing["first"] != 2 || report.Missing["second"] != 2 || report.Repaired != 0 {
+		t.Fatalf("dry run = %+v, want 2 scanned and 2 missing on each replica", *report)
+	}
+	if exists(t, first, "rec/b.mp3") {
+		t.Fatal("dry run copied an object")
+	}
+
+	report, err = mirror.Repair(ctx, "rec/", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Repaired != 4 || report.Failed != 0 {
+		t.Fatalf("repair = %+v, want 4 repaired", *report)
+	}
+	for _, replica := range []Provider{first, second} {
+		for _, key := range []string{"rec/a.mp3", "rec/b.mp3"} {
+			if got := readAll(t, replica, key); got != "audio "+key {
+				t.Fatalf("replica holds %q for %s after repair", got, key)
+			}
+		}
+		if exists(t, replica, "other/c.mp3") {
+			t.Fatal("repair went outside the prefix")
+		}
+	}
+	if !exists(t, second, "rec/stray.mp3") {
+		t.Fatal("repair pruned a replica")
+	}
+
+	report, err = mirror.Repair(ctx, "rec/", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Missing["first"] != 0 || report.Missing["second"] != 0 || report.Repaired != 0 {
+		t.Fatalf("second repair = %+v, want nothing missing", *report)
+	}
+}
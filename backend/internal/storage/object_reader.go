@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ObjectReader is an io.ReadSeeker over a stored object that fetches only the
+// bytes that are read. Each seek reopens the object at the new offset, so
+// http.ServeContent can answer Range requests without downloading everything.
+type ObjectReader struct {
+	ctx    context.Context
+	store  Provider
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func NewObjectReader(ctx context.Context, store Provider, info *ObjectInfo) *ObjectReader {
+	return &ObjectReader{ctx: ctx, store: store, key: info.Key, size: info.Size}
+}
+
+func (r *ObjectReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := r.store.Open(r.ctx, r.key, r.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *ObjectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+	if offset < 0 {
+		return 0, errors.New("seek before start of object")
+	}
+
+	if offset != r.offset {
+		r.Close()
+		r.offset = offset
+	}
+	return offset, nil
+}
+
+func (r *ObjectReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// PrefixedProvider keeps every key under a fixed prefix of the wrapped
+// provider, so a shared bucket can hold an isolated area per customer
+type PrefixedProvider struct {
+	inner  Provider
+	prefix string
+}
+
+var _ Provider = (*PrefixedProvider)(nil)
+
+// NewPrefixedProvider returns inner unchanged for an empty prefix
+func NewPrefixedProvider(inner Provider, prefix string) Provider {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return inner
+	}
+	return &PrefixedProvider{inner: inner, prefix: prefix + "/"}
+}
+
+func (p *PrefixedProvider) Unwrap() Provider {
+	return p.inner
+}
+
+func (p *PrefixedProvider) Upload(ctx context.Context, file io.Reader, key string, contentType string) (*UploadResult, error) {
+	result, err := p.inner.Upload(ctx, file, p.prefix+key, contentType)
+	if err != nil {
+		return nil, err
+	}
+	result.Key = key
+	return result, nil
+}
+
+func (p *PrefixedProvider) Delete(ctx context.Context, key string) error {
+	return p.inner.Delete(ctx, p.prefix+key)
+}
+
+func (p *PrefixedProvider) Copy(ctx context.Context, srcKey string, dstKey string) error {
+	return p.inner.Copy(ctx, p.prefix+srcKey, p.prefix+dstKey)
+}
+
+func (p *PrefixedProvider) GetSignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error) {
+	return p.inner.GetSignedURL(ctx, p.prefix+key, opts)
+}
+
+func (p *PrefixedProvider) GetSignedUploadURL(ctx context.Context, key string, contentType string, size int64, ttl time.Duration) (*SignedUpload, error) {
+	return p.inner.GetSignedUploadURL(ctx, p.prefix+key, contentType, size, ttl)
+}
+
+func (p *PrefixedProvider) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := p.inner.Stat(ctx, p.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	info.Key = key
+	return info, nil
+}
+
+func (p *PrefixedProvider) Open(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	return p.inner.Open(ctx, p.prefix+key, offset, length)
+}
+
+func (p *PrefixedProvider) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	return p.inner.List(ctx, p.prefix+prefix, func(object ObjectInfo) error {
+		object.Key = strings.TrimPrefix(object.Key, p.prefix)
+		return fn(object)
+	})
+}
+
+func (p *PrefixedProvider) CreateMultipartUpload(ctx context.Context, key string, contentType string) (string, error) {
+	return p.inner.CreateMultipartUpload(ctx, p.prefix+key, contentType)
+}
+
+func (p *PrefixedProvider) UploadPart(ctx context.Context, key string, uploadID string, number int, part io.ReadSeeker, size int64) (*CompletedPart, error) {
+	return p.inner.UploadPart(ctx, p.prefix+key, uploadID, number, part, size)
+}
+
+func (p *PrefixedProvider) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []CompletedPart) (*UploadResult, error) {
+	result, err := p.inner.CompleteMultipartUpload(ctx, p.prefix+key, uploadID, parts)
+	if err != nil {
+		return nil, err
+	}
+	result.Key = key
+	return result, nil
+}
+
+func (p *PrefixedProvider) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	return p.inner.AbortMultipartUpload(ctx, p.prefix+key, uploadID)
+}
@@ -2,9 +2,22 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"io"
+	"mime"
+	"time"
 )
 
+// DefaultSignedURLTTL is used when no expiry is requested for a signed URL
+const DefaultSignedURLTTL = 15 * time.Minute
+
+// MinPartSize is the smallest part a multipart upload accepts, except for the last
+// one. It matches the S3 limit so every provider behaves the same way.
+const MinPartSize = 5 << 20
+
+// ErrObjectNotFound is returned when a key does not exist in storage
+var ErrObjectNotFound = errors.New("object not found")
+
 type UploadResult struct {
 	Key      string
 	URL      string
@@ -12,8 +25,63 @@ type UploadResult struct {
 	MimeType string
 }
 
+// SignedURLOptions customises a signed download link
+type SignedURLOptions struct {
+	Expires     time.Duration // Falls back to DefaultSignedURLTTL when zero
+	Filename    string        // Served as Content-Disposition so the browser keeps a readable name
+	ContentType string        // Overrides the Content-Type stored with the object
+}
+
+func (o SignedURLOptions) ttl() time.Duration {
+	if o.Expires <= 0 {
+		return DefaultSignedURLTTL
+	}
+	return o.Expires
+}
+
+// ObjectInfo describes an object that is already in storage
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// SignedUpload is a presigned request a client can send an object with directly
+type SignedUpload struct {
+	URL     string
+	Method  string
+	Headers map[string]string // Must be sent exactly as given, they are part of the signature
+}
+
+// CompletedPart identifies one stored part of a multipart upload
+type CompletedPart struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
 type Provider interface {
 	Upload(ctx context.Context, file io.Reader, key string, contentType string) (*UploadResult, error)
 	Delete(ctx context.Context, key string) error
-	GetSignedURL(ctx context.Context, key string) (string, error)
+	Copy(ctx context.Context, srcKey string, dstKey string) error
+	GetSignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error)
+	GetSignedUploadURL(ctx context.Context, key string, contentType string, size int64, ttl time.Duration) (*SignedUpload, error)
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// Open reads length bytes starting at offset; a negative length reads to the end
+	Open(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error)
+	// List calls fn for every object whose key starts with prefix, stopping at the first error
+	List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+
+	// Multipart uploads assemble one object from parts sent over several requests
+	CreateMultipartUpload(ctx context.Context, key string, contentType string) (string, error)
+	UploadPart(ctx context.Context, key string, uploadID string, number int, part io.ReadSeeker, size int64) (*CompletedPart, error)
+	CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []CompletedPart) (*UploadResult, error)
+	AbortMultipartUpload(ctx context.Context, key string, uploadID string) error
+}
+
+// ContentDisposition builds an attachment header that survives non-ASCII filenames
+func ContentDisposition(filename string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": filename})
 }
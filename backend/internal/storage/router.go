@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// profileKeyPrefix marks keys that live in a storage profile instead of the
+// default store, e.g. "profiles/12/3f9a....mp3"
+const profileKeyPrefix = "profiles/"
+
+// ProfileKey places key inside a storage profile. Profile 0 is the default store.
+func ProfileKey(profileID uint, key string) string {
+	if profileID == 0 {
+		return key
+	}
+	return profileKeyPrefix + strconv.FormatUint(uint64(profileID), 10) + "/" + key
+}
+
+// ParseProfileKey splits a profile key into the profile and the key inside it
+func ParseProfileKey(key string) (uint, string, bool) {
+	rest, ok := strings.CutPrefix(key, profileKeyPrefix)
+	if !ok {
+		return 0, "", false
+	}
+	id, inner, ok := strings.Cut(rest, "/")
+	if !ok {
+		return 0, "", false
+	}
+	profileID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || profileID == 0 {
+		return 0, "", false
+	}
+	return uint(profileID), inner, true
+}
+
+// ProfileResolver builds the provider behind a storage profile
+type ProfileResolver interface {
+	ResolveProfile(ctx context.Context, profileID uint) (Provider, error)
+}
+
+// Router sends each key to the store it belongs to. Profile keys go to the
+// provider their profile resolves to, everything else to the default store.
+// Because the profile is part of the key, anything that stores a key (files,
+// meetings, queued jobs) can be read back from the right bucket later on.
+type Router struct {
+	fallback Provider
+	resolver ProfileResolver
+
+	mu       sync.Mutex
+	profiles map[uint]Provider
+}
+
+var _ Provider = (*Router)(nil)
+
+func NewRouter(fallback Provider, resolver ProfileResolver) *Router {
+	return &Router{
+		fallback: fallback,
+		resolver: resolver,
+		profiles: make(map[uint]Provider),
+	}
+}
+
+// Unwrap returns the default store
+func (r *Router) Unwrap() Provider {
+	return r.fallback
+}
+
+// Forget drops the cached provider of a profile, e.g. after its credentials change
+func (r *Router) Forget(profileID uint) {
+	r.mu.Lock()
+	delete(r.profiles, profileID)
+	r.mu.Unlock()
+}
+
+// route returns the store that holds key and the key within it
+func (r *Router) route(ctx context.Context, key string) (Provider, string, error) {
+	profileID, inner, ok := ParseProfileKey(key)
+	if !ok {
+		return r.fallback, key, nil
+	}
+	store, err := r.profile(ctx, profileID)
+	if err != nil {
+		return nil, "", err
+	}
+	return store, inner, nil
+}
+
+func (r *Router) profile(ctx context.Context, profileID uint) (Provider, error) {
+	r.mu.Lock()
+	store, ok := r.profiles[profileID]
+	r.mu.Unlock()
+	if ok {
+		return store, nil
+	}
+
+	store, err := r.resolver.ResolveProfile(ctx, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage profile %d: %w", profileID, err)
+	}
+
+	r.mu.Lock()
+	r.profiles[profileID] = store
+	r.mu.Unlock()
+	return store, nil
+}
+
+func (r *Router) Upload(ctx context.Context, file io.Reader, key string, contentType string) (*UploadResult, error) {
+	store, inner, err := r.route(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	result, err := store.Upload(ctx, file, inner, contentType)
+	if err != nil {
+		return nil, err
+	}
+	result.Key = key
+	return result, nil
+}
+
+func (r *Router) Delete(ctx context.Context, key string) error {
+	store, inner, err := r.route(ctx, key)
+	if err != nil {
+		return err
+	}
+	return store.Delete(ctx, inner)
+}
+
+// Copy streams the object through the server when the keys are in different stores
+func (r *Router) Copy(ctx context.Context, srcKey string, dstKey string) error {
+	src, srcInner, err := r.route(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	dst, dstInner, err := r.route(ctx, dstKey)
+	if err != nil {
+		return err
+	}
+	if src == dst {
+		return src.Copy(ctx, srcInner, dstInner)
+	}
+
+	info, err := src.Stat(ctx, srcInner)
+	if err != nil {
+		return err
+	}
+	body, err := src.Open(ctx, srcInner, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	_, err = dst.Upload(ctx, body, dstInner, info.ContentType)
+	return err
+}
+
+func (r *Router) GetSignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error) {
+	store, inner, err := r.route(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return store.GetSignedURL(ctx, inner, opts)
+}
+
+func (r *Router) GetSignedUploadURL(ctx context.Context, key string, contentType string, size int64, ttl time.Duration) (*SignedUpload, error) {
+	store, inner, err := r.route(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetSignedUploadURL(ctx, inner, contentType, size, ttl)
+}
+
+func (r *Router) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	store, inner, err := r.route(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := store.Stat(ctx, inner)
+	if err != nil {
+		return nil, err
+	}
+	info.Key = key
+	return info, nil
+}
+
+func (r *Router) Open(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	store, inner, err := r.route(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return store.Open(ctx, inner, offset, length)
+}
+
+// List only covers one profile when prefix names it. Otherwise it lists the
+// default store and skips profile areas kept there.
+func (r *Router) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	if profileID, inner, ok := ParseProfileKey(prefix); ok {
+		store, err := r.profile(ctx, profileID)
+		if err != nil {
+			return err
+		}
+		return store.List(ctx, inner, func(object ObjectInfo) error {
+			object.Key = ProfileKey(profileID, object.Key)
+			return fn(object)
+		})
+	}
+
+	return r.fallback.List(ctx, prefix, func(object ObjectInfo) error {
+		if strings.HasPrefix(object.Key, profileKeyPrefix) {
+			return nil
+		}
+		return fn(object)
+	})
+}
+
+func (r *Router) CreateMultipartUpload(ctx context.Context, key string, contentType string) (string, error) {
+	store, inner, err := r.route(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return store.CreateMultipartUpload(ctx, inner, contentType)
+}
+
+func (r *Router) UploadPart(ctx context.Context, key string, uploadID string, number int, part io.ReadSeeker, size int64) (*CompletedPart, error) {
+	store, inner, err := r.route(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return store.UploadPart(ctx, inner, uploadID, number, part, size)
+}
+
+func (r *Router) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []CompletedPart) (*UploadResult, error) {
+	store, inner, err := r.route(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	result, err := store.CompleteMultipartUpload(ctx, inner, uploadID, parts)
+	if err != nil {
+		return nil, err
+	}
+	result.Key = key
+	return result, nil
+}
+
+func (r *Router) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	store, inner, err := r.route(ctx, key)
+	if err != nil {
+		return err
+	}
+	return store.AbortMultipartUpload(ctx, inner, uploadID)
+}
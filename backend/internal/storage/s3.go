@@ -1,10 +1,13 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"time"
@@ -13,11 +16,16 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 type S3Provider struct {
-	client *s3.Client
-	bucket string
+	client  *s3.Client
+	presign *s3.PresignClient
+	bucket  string
+	// Storage class for new objects; empty uses the bucket default
+	storageClass types.StorageClass
+	dialer       *net.Dialer
 }
 
 // Ensure S3Provider satisfies the interface at compile time
@@ -33,10 +41,15 @@ func NewS3Provider(ctx context.Context, bucket, endpoint, region, accessKey, sec
 		return nil, fmt.Errorf("invalid endpoint URL: %w", err)
 	}
 
-	// Create a custom HTTP client with proper timeout
-	httpClient := &http.Client{
-		Timeout: 30 * time.Second,
-	}
+	// Only connecting and waiting for the response are bounded here. An overall
+	// client timeout would also cut off long downloads mid-stream, so each call
+	// is bounded by its own context instead.
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = 10 * time.Second
+	transport.ResponseHeaderTimeout = 30 * time.Second
+	httpClient := &http.Client{Transport: transport}
 
 	cfg, err := config.LoadDefaultConfig(ctx,
 		config.WithRegion(region),
@@ -63,20 +76,62 @@ func NewS3Provider(ctx context.Context, bucket, endpoint, region, accessKey, sec
 
 	log.Printf("S3 provider initialized successfully with endpoint: %s", endpoint)
 	return &S3Provider{
-		client: client,
-		bucket: bucket,
+		client:  client,
+		presign: s3.NewPresignClient(client),
+		bucket:  bucket,
+		dialer:  dialer,
 	}, nil
 }
 
+// RestrictToPublicAddresses refuses connections to private, loopback and
+// link-local addresses, for endpoints a user supplied
+func (p *S3Provider) RestrictToPublicAddresses() {
+	p.dialer.Control = rejectPrivateDial
+}
+
+// SetStorageClass stores new objects in the given S3 storage class, e.g. STANDARD_IA
+func (p *S3Provider) SetStorageClass(class string) {
+	p.storageClass = types.StorageClass(class)
+}
+
 func (p *S3Provider) Upload(ctx context.Context, file io.Reader, key string, contentType string) (*UploadResult, error) {
 	log.Printf("Uploading file to bucket: %s, key: %s", p.bucket, key)
 
+	if seeker, ok := file.(io.ReadSeeker); ok {
+		return p.putObject(ctx, seeker, key, contentType)
+	}
+
+	// Streams can't be rewound for signing, so read one part ahead:
+	// small files still go up in a single PutObject, larger ones in parts
+	buf := make([]byte, MinPartSize)
+	n, err := io.ReadFull(file, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return p.putObject(ctx, bytes.NewReader(buf[:n]), key, contentType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	return p.uploadStream(ctx, io.MultiReader(bytes.NewReader(buf), file), key, contentType)
+}
+
+func (p *S3Provider) putObject(ctx context.Context, file io.ReadSeeker, key string, contentType string) (*UploadResult, error) {
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure upload: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind upload: %w", err)
+	}
+
 	// Upload the file to S3
-	_, err := p.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(p.bucket),
-		Key:         aws.String(key),
-		Body:        file,
-		ContentType: aws.String(contentType),
+	_, err = p.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(p.bucket),
+		Key:           aws.String(key),
+		Body:          file,
+		ContentLength: aws.Int64(size),
+		ContentType:   aws.String(contentType),
+		StorageClass:  p.storageClass,
 	})
 	if err != nil {
 		log.Printf("S3 PutObject error: %v", err)
@@ -89,16 +144,267 @@ func (p *S3Provider) Upload(ctx context.Context, file io.Reader, key string, con
 	return &UploadResult{
 		Key:      key,
 		URL:      "", // Can be populated with GetSignedURL if needed
+		Size:     size,
 		MimeType: contentType,
 	}, nil
 }
 
+// uploadStream sends a stream of unknown length as a multipart upload, holding
+// at most one part in memory
+func (p *S3Provider) uploadStream(ctx context.Context, file io.Reader, key string, contentType string) (*UploadResult, error) {
+	uploadID, err := p.CreateMultipartUpload(ctx, key, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []CompletedPart
+	buf := make([]byte, MinPartSize)
+	for {
+		n, readErr := io.ReadFull(file, buf)
+		if n > 0 {
+			part, err := p.UploadPart(ctx, key, uploadID, len(parts)+1, bytes.NewReader(buf[:n]), int64(n))
+			if err != nil {
+				p.AbortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+				return nil, err
+			}
+			parts = append(parts, *part)
+		}
+
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if readErr != nil {
+			p.AbortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+			return nil, fmt.Errorf("failed to read upload: %w", readErr)
+		}
+	}
+
+	result, err := p.CompleteMultipartUpload(ctx, key, uploadID, parts)
+	if err != nil {
+		p.AbortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+		return nil, err
+	}
+	result.MimeType = contentType
+	return result, nil
+}
+
 func (p *S3Provider) Delete(ctx context.Context, key string) error {
-	// TODO: Implement
+	log.Printf("Deleting file from bucket: %s, key: %s", p.bucket, key)
+
+	// S3 treats deleting a missing key as success, so retries are safe
+	_, err := p.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		log.Printf("S3 DeleteObject error: %v", err)
+		return fmt.Errorf("failed to delete from S3: %w", err)
+	}
+
+	return nil
+}
+
+func (p *S3Provider) Copy(ctx context.Context, srcKey string, dstKey string) error {
+	// Server-side copy, the bytes never leave the bucket
+	_, err := p.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:       aws.String(p.bucket),
+		Key:          aws.String(dstKey),
+		CopySource:   aws.String(p.bucket + "/" + escapeKey(srcKey)),
+		StorageClass: p.storageClass,
+	})
+	if err != nil {
+		log.Printf("S3 CopyObject error: %v", err)
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+	return nil
+}
+
+func (p *S3Provider) GetSignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	}
+
+	// Response overrides are baked into the signature, so they can't be tampered with
+	if opts.Filename != "" {
+		input.ResponseContentDisposition = aws.String(ContentDisposition(opts.Filename))
+	}
+	if opts.ContentType != "" {
+		input.ResponseContentType = aws.String(opts.ContentType)
+	}
+
+	req, err := p.presign.PresignGetObject(ctx, input, s3.WithPresignExpires(opts.ttl()))
+	if err != nil {
+		log.Printf("S3 presign error: %v", err)
+		return "", fmt.Errorf("failed to presign URL: %w", err)
+	}
+
+	return req.URL, nil
+}
+
+func (p *S3Provider) GetSignedUploadURL(ctx context.Context, key string, contentType string, size int64, ttl time.Duration) (*SignedUpload, error) {
+	// Content-Type and Content-Length become signed headers, so S3 rejects anything else
+	req, err := p.presign.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(p.bucket),
+		Key:           aws.String(key),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(size),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		log.Printf("S3 presign upload error: %v", err)
+		return nil, fmt.Errorf("failed to presign upload: %w", err)
+	}
+
+	return &SignedUpload{
+		URL:     req.URL,
+		Method:  req.Method,
+		Headers: map[string]string{"Content-Type": contentType},
+	}, nil
+}
+
+func (p *S3Provider) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	out, err := p.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, ErrObjectNotFound
+		}
+		log.Printf("S3 HeadObject error: %v", err)
+		return nil, fmt.Errorf("failed to stat object: %w", err)
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(out.ContentLength),
+		ContentType:  aws.ToString(out.ContentType),
+		ETag:         aws.ToString(out.ETag),
+		LastModified: aws.ToTime(out.LastModified),
+	}, nil
+}
+
+func (p *S3Provider) Open(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	}
+	switch {
+	case length == 0:
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	case length > 0:
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	case offset > 0:
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	out, err := p.client.GetObject(ctx, input)
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrObjectNotFound
+		}
+		log.Printf("S3 GetObject error: %v", err)
+		return nil, fmt.Errorf("failed to open object: %w", err)
+	}
+	return out.Body, nil
+}
+
+func (p *S3Provider) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	paginator := s3.NewListObjectsV2Paginator(p.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(p.bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("S3 ListObjectsV2 error: %v", err)
+			return fmt.Errorf("failed to list objects: %w", err)
+		}
+		for _, object := range page.Contents {
+			err := fn(ObjectInfo{
+				Key:          aws.ToString(object.Key),
+				Size:         aws.ToInt64(object.Size),
+				ETag:         aws.ToString(object.ETag),
+				LastModified: aws.ToTime(object.LastModified),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
-func (p *S3Provider) GetSignedURL(ctx context.Context, key string) (string, error) {
-	// TODO: Implement
-	return "", nil
+func (p *S3Provider) CreateMultipartUpload(ctx context.Context, key string, contentType string) (string, error) {
+	out, err := p.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:       aws.String(p.bucket),
+		Key:          aws.String(key),
+		ContentType:  aws.String(contentType),
+		StorageClass: p.storageClass,
+	})
+	if err != nil {
+		log.Printf("S3 CreateMultipartUpload error: %v", err)
+		return "", fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+
+	return aws.ToString(out.UploadId), nil
+}
+
+func (p *S3Provider) UploadPart(ctx context.Context, key string, uploadID string, number int, part io.ReadSeeker, size int64) (*CompletedPart, error) {
+	out, err := p.client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(p.bucket),
+		Key:           aws.String(key),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int32(int32(number)),
+		Body:          part,
+		ContentLength: aws.Int64(size),
+	})
+	if err != nil {
+		log.Printf("S3 UploadPart error: %v", err)
+		return nil, fmt.Errorf("failed to upload part %d: %w", number, err)
+	}
+
+	return &CompletedPart{Number: number, ETag: aws.ToString(out.ETag), Size: size}, nil
+}
+
+func (p *S3Provider) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []CompletedPart) (*UploadResult, error) {
+	completed := make([]types.CompletedPart, len(parts))
+	var size int64
+	for i, part := range parts {
+		completed[i] = types.CompletedPart{
+			PartNumber: aws.Int32(int32(part.Number)),
+			ETag:       aws.String(part.ETag),
+		}
+		size += part.Size
+	}
+
+	_, err := p.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(p.bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		log.Printf("S3 CompleteMultipartUpload error: %v", err)
+		return nil, fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	log.Printf("Successfully assembled file: %s (%d parts)", key, len(parts))
+	return &UploadResult{Key: key, Size: size}, nil
+}
+
+func (p *S3Provider) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	_, err := p.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(p.bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		log.Printf("S3 AbortMultipartUpload error: %v", err)
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// TieredProvider keeps new objects in a hot store and lets old ones be moved
+// to a cheaper cold store. Every read tries the hot store first and falls
+// back to the cold one, so an archived object is still served under its key.
+// Replicas of the hot store keep archived objects as their recovery copy, so
+// they are only read once neither tier has the object.
+// Which tier an object is in is tracked on its file row; the provider itself
+// only needs to know where to look.
+//
+// The cold store must be readable straight away, e.g. a second bucket or an
+// S3 storage class with instant retrieval such as GLACIER_IR.
+type TieredProvider struct {
+	hot  Provider
+	cold Provider
+}
+
+var _ Provider = (*TieredProvider)(nil)
+
+func NewTieredProvider(hot, cold Provider) *TieredProvider {
+	log.Printf("Tiered storage initialized")
+	return &TieredProvider{hot: hot, cold: cold}
+}
+
+// Unwrap returns the hot store
+func (p *TieredProvider) Unwrap() Provider {
+	return p.hot
+}
+
+// Tiers returns the hot and cold stores
+func (p *TieredProvider) Tiers() (hot, cold Provider) {
+	return p.hot, p.cold
+}
+
+// Archive moves an object to the cold store. It is safe to call again after
+// an interrupted move.
+func (p *TieredProvider) Archive(ctx context.Context, key string) error {
+	if err := p.move(ctx, p.hot, p.cold, key); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", key, err)
+	}
+	return nil
+}
+
+// Restore moves an archived object back to the hot store
+func (p *TieredProvider) Restore(ctx context.Context, key string) error {
+	if err := p.move(ctx, p.cold, p.hot, key); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", key, err)
+	}
+	return nil
+}
+
+// move copies key from src to dst, checks the copy and deletes the original.
+// An object that is only in dst already counts as moved. Replicas are left
+// alone, since the cold tier has none of its own.
+func (p *TieredProvider) move(ctx context.Context, src, dst Provider, key string) error {
+	ctx = primaryOnly(ctx)
+	info, err := src.Stat(ctx, key)
+	if errors.Is(err, ErrObjectNotFound) {
+		_, err = dst.Stat(ctx, key)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	body, err := src.Open(ctx, key, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	result, err := dst.Upload(ctx, body, key, info.ContentType)
+	if err != nil {
+		return err
+	}
+	if result.Size != info.Size {
+		dst.Delete(context.WithoutCancel(ctx), key)
+		return fmt.Errorf("copied %d of %d bytes", result.Size, info.Size)
+	}
+	return src.Delete(ctx, key)
+}
+
+func (p *TieredProvider) Upload(ctx context.Context, file io.Reader, key string, contentType string) (*UploadResult, error) {
+	return p.hot.Upload(ctx, file, key, contentType)
+}
+
+// Delete removes the object from both tiers, since it may be in either
+func (p *TieredProvider) Delete(ctx context.Context, key string) error {
+	if err := p.hot.Delete(ctx, key); err != nil {
+		return err
+	}
+	return p.cold.Delete(ctx, key)
+}
+
+// Copy writes to the hot store, reading the source from the cold one if needed
+func (p *TieredProvider) Copy(ctx context.Context, srcKey string, dstKey string) error {
+	if _, err := p.hot.Stat(primaryOnly(ctx), srcKey); !errors.Is(err, ErrObjectNotFound) {
+		return p.hot.Copy(ctx, srcKey, dstKey)
+	}
+
+	info, err := p.cold.Stat(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	body, err := p.cold.Open(ctx, srcKey, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	_, err = p.hot.Upload(ctx, body, dstKey, info.ContentType)
+	return err
+}
+
+// GetSignedURL points at whichever tier holds the object. Signing doesn't
+// check the object exists, so the hot store is asked first.
+func (p *TieredProvider) GetSignedURL(ctx context.Context, key string, opts SignedURLOptions) (string, error) {
+	if _, err := p.hot.Stat(primaryOnly(ctx), key); errors.Is(err, ErrObjectNotFound) {
+		return p.cold.GetSignedURL(ctx, key, opts)
+	}
+	return p.hot.GetSignedURL(ctx, key, opts)
+}
+
+func (p *TieredProvider) GetSignedUploadURL(ctx context.Context, key string, contentType string, size int64, ttl time.Duration) (*SignedUpload, error) {
+	return p.hot.GetSignedUploadURL(ctx, key, contentType, size, ttl)
+}
+
+func (p *TieredProvider) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := p.hot.Stat(primaryOnly(ctx), key)
+	if err == nil {
+		return info, nil
+	}
+	if errors.Is(err, ErrObjectNotFound) {
+		info, err = p.cold.Stat(ctx, key)
+		if !errors.Is(err, ErrObjectNotFound) {
+			return info, err
+		}
+	}
+	return p.hot.Stat(ctx, key)
+}
+
+func (p *TieredProvider) Open(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	body, err := p.hot.Open(primaryOnly(ctx), key, offset, length)
+	if err == nil {
+		return body, nil
+	}
+	if errors.Is(err, ErrObjectNotFound) {
+		body, err = p.cold.Open(ctx, key, offset, length)
+		if !errors.Is(err, ErrObjectNotFound) {
+			return body, err
+		}
+	}
+	return p.hot.Open(ctx, key, offset, length)
+}
+
+// List covers both tiers. An object caught mid-move may be listed twice.
+func (p *TieredProvider) List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error {
+	if err := p.hot.List(ctx, prefix, fn); err != nil {
+		return err
+	}
+	return p.cold.List(ctx, prefix, fn)
+}
+
+func (p *TieredProvider) CreateMultipartUpload(ctx context.Context, key string, contentType string) (string, error) {
+	return p.hot.CreateMultipartUpload(ctx, key, contentType)
+}
+
+func (p *TieredProvider) UploadPart(ctx context.Context, key string, uploadID string, number int, part io.ReadSeeker, size int64) (*CompletedPart, error) {
+	return p.hot.UploadPart(ctx, key, uploadID, number, part, size)
+}
+
+func (p *TieredProvider) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []CompletedPart) (*UploadResult, error) {
+	return p.hot.CompleteMultipartUpload(ctx, key, uploadID, parts)
+}
+
+func (p *TieredProvider) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	return p.hot.AbortMultipartUpload(ctx, key, uploadID)
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func newLocal(t *testing.T) *LocalDiskProvider {
+	t.Helper()
+	store, err := NewLocalDiskProvider(t.TempDir(), "http://localhost", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func readAll(t *testing.T, store Provider, key string) string {
+	t.Helper()
+	body, err := store.Open(context.Background(), key, 0, -1)
+	if err != nil {
+		t.Fatalf("open %s: %v", key, err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func exists(t *testing.T, store Provider, key string) bool {
+	t.Helper()
+	_, err := store.Stat(context.Background(), key)
+	if err != nil && !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("stat %s: %v", key, err)
+	}
+	return err == nil
+}
+
+func TestTieredArchiveKeepsReplicas(t *testing.T) {
+	ctx := context.Background()
+	primary, replica, cold := newLocal(t), newLocal(t), newLocal(t)
+	hot, err := NewMirroredProvider(primary, []NamedProvider{{Name: "dr", Store: replica}}, ReplicateSync)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tiered := NewTieredProvider(hot, cold)
+
+	const key = "recordings/a.mp3"
+	if _, err := tiered.Upload(ctx, strings.NewReader("audio"), key, "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tiered.Archive(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+
+	if exists(t, primary, key) || !exists(t, cold, key) {
+		t.Fatal("archive did not move the object to the cold tier")
+	}
+	if !exists(t, replica, key) {
+		t.Fatal("archive deleted the replica copy")
+	}
+	if got := readAll(t, tiered, key); got != "audio" {
+		t.Fatalf("read %q after archive", got)
+	}
+
+	// Archiving again must not mistake the replica for the hot copy
+	if err := tiered.Archive(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(t, cold, key) {
+		t.Fatal("second archive lost the cold copy")
+	}
+
+	var listed []string
+	err = tiered.List(ctx, "recordings/", func(object ObjectInfo) error {
+		listed = append(listed, object.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0] != key {
+		t.Fatalf("listed %v, want the archived object", listed)
+	}
+
+	if err := tiered.Restore(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(t, primary, key) || exists(t, cold, key) || !exists(t, replica, key) {
+		t.Fatal("restore did not move the object back to the hot tier")
+	}
+
+	if err := tiered.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if exists(t, primary, key) || exists(t, cold, key) || exists(t, replica, key) {
+		t.Fatal("delete left a copy behind")
+	}
+}
+
+func TestTieredReadsReplicaWhenBothTiersLostTheObject(t *testing.T) {
+	ctx := context.Background()
+	primary, replica, cold := newLocal(t), newLocal(t), newLocal(t)
+	hot, err := NewMirroredProvider(primary, []NamedProvider{{Name: "dr", Store: replica}}, ReplicateSync)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tiered := NewTieredProvider(hot, cold)
+
+	const key = "recordings/b.mp3"
+	if _, err := tiered.Upload(ctx, strings.NewReader("audio"), key, "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tiered.Archive(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := cold.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readAll(t, tiered, key); got != "audio" {
+		t.Fatalf("read %q from the replica", got)
+	}
+}
@@ -1,9 +1,14 @@
 import Link from "next/link";
-import { getMeeting } from "@/requests/meeting";
+import { getMeeting, getTranscript } from "@/requests/meeting";
 import { Button } from "@/components/ui/button";
 import { DeleteMeetingButton } from "@/components/DeleteMeetingButton";
 import { AudioUploadDropzone } from "@/components/AudioUploadDropzone";
-import { MeetingStatus, type Meeting as MeetingType } from "@/types/meeting";
+import { MeetingProgress } from "@/components/MeetingProgress";
+import {
+  MeetingStatus,
+  StorageTier,
+  type Meeting as MeetingType,
+} from "@/types/meeting";
 import { Download } from "lucide-react";
 import { formatDateTime, formatDuration } from "@/utils/time";
 import { formatFileSize } from "@/utils/file";
@@ -11,6 +16,8 @@ import { formatFileSize } from "@/utils/file";
 function StatusBadge({ status }: { status: MeetingStatus }) {
   const statusStyles = {
     [MeetingStatus.CREATED]: "bg-blue-500/10 text-blue-500 dark:bg-blue-500/20",
+    [MeetingStatus.QUEUED]:
+      "bg-purple-500/10 text-purple-500 dark:bg-purple-500/20",
     [MeetingStatus.PROCESSING]:
       "bg-yellow-500/10 text-yellow-500 dark:bg-yellow-500/20",
     [MeetingStatus.COMPLETED]:
@@ -26,6 +33,15 @@ function StatusBadge({ status }: { status: MeetingStatus }) {
   );
 }
 
+// Old recordings move to cold storage and are restored when played again
+function TierBadge({ tier }: { tier: StorageTier }) {
+  return (
+    <span className="inline-flex items-center rounded-full bg-slate-500/10 px-2.5 py-0.5 text-xs font-medium text-slate-500 dark:bg-slate-500/20">
+      {tier === StorageTier.RESTORING ? "archived, restoring" : "archived"}
+    </span>
+  );
+}
+
 function Section({
   title,
   children,
@@ -85,9 +101,18 @@ export default async function MeetingPage({
     );
   }
 
+  let transcript = meeting.transcript ?? null;
+  if (transcript === null && meeting.has_transcript) {
+    try {
+      transcript = await getTranscript(meeting.id);
+    } catch (error) {
+      console.error("Failed to fetch transcript:", error);
+    }
+  }
+
   const hasAiOutput =
     Boolean(meeting.summary) ||
-    Boolean(meeting.transcript) ||
+    Boolean(transcript) ||
     (meeting.action_items?.length ?? 0) > 0;
 
   // Check if meeting is in the past (scheduled_at is older than current time)
@@ -128,6 +153,20 @@ export default async function MeetingPage({
       </div>
 
       <div className="grid gap-6">
+        {meeting.status === MeetingStatus.FAILED && meeting.failure_reason && (
+          <div className="rounded-lg border border-red-500/50 bg-red-500/10 p-4 text-sm text-red-500">
+            <p className="font-medium">Processing failed</p>
+            <p className="mt-1 whitespace-pre-wrap">{meeting.failure_reason}</p>
+          </div>
+        )}
+
+        {(meeting.status === MeetingStatus.QUEUED ||
+          meeting.status === MeetingStatus.PROCESSING) && (
+          <Section title="Processing">
+            <MeetingProgress meetingId={meeting.id} />
+          </Section>
+        )}
+
         {showUpload && (
           <Section title="Upload Recording">
             <AudioUploadDropzone meetingId={meeting.id} />
@@ -208,6 +247,17 @@ export default async function MeetingPage({
                     {meeting.recording_path}
                   </dd>
                 </div>
+                {meeting.recording_tier &&
+                  meeting.recording_tier !== StorageTier.HOT && (
+                    <div>
+                      <dt className="text-xs font-medium text-muted-foreground">
+                        Storage
+                      </dt>
+                      <dd className="mt-1 text-sm">
+                        <TierBadge tier={meeting.recording_tier} />
+                      </dd>
+                    </div>
+                  )}
                 <div>
                   <dt className="text-xs font-medium text-muted-foreground">
                     File Size
@@ -225,6 +275,14 @@ export default async function MeetingPage({
                   </dd>
                 </div>
               </dl>
+              <audio
+                controls
+                preload="metadata"
+                className="w-full"
+                src={`${
+                  process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080"
+                }/api/v1/meetings/${meeting.id}/recording`}
+              />
               <div className="pt-2">
                 <Button variant="outline" asChild className="w-full sm:w-auto">
                   <a
@@ -272,11 +330,11 @@ export default async function MeetingPage({
                 </div>
               )}
 
-              {meeting.transcript && (
+              {transcript && (
                 <div>
                   <h3 className="text-sm font-semibold">Transcript</h3>
                   <pre className="mt-2 max-h-[60vh] overflow-auto whitespace-pre-wrap rounded-md border bg-background p-4 text-sm text-muted-foreground">
-                    {meeting.transcript}
+                    {transcript}
                   </pre>
                 </div>
               )}
@@ -28,6 +28,8 @@ export default async function Dashboard() {
     const statusStyles = {
       [MeetingStatus.CREATED]:
         "bg-blue-500/10 text-blue-500 dark:bg-blue-500/20",
+      [MeetingStatus.QUEUED]:
+        "bg-purple-500/10 text-purple-500 dark:bg-purple-500/20",
       [MeetingStatus.PROCESSING]:
         "bg-yellow-500/10 text-yellow-500 dark:bg-yellow-500/20",
       [MeetingStatus.COMPLETED]:
@@ -42,9 +42,9 @@ export function AudioUploadDropzone({
           setUploadProgress(progress);
         });
 
-        // Step 3: Update meeting with recording_path, size, and duration
+        // Step 3: Link the uploaded file, with its size and duration
         await updateMeeting(meetingId, {
-          recording_path: uploadResponse.file_id,
+          recording_file_id: uploadResponse.id,
           recording_size_bytes: file.size,
           recording_duration_seconds: duration,
         });
@@ -0,0 +1,79 @@
+"use client";
+
+import { useRouter } from "next/navigation";
+import { useEffect, useState } from "react";
+import { meetingEventsUrl } from "@/requests/meeting";
+import { MeetingStatus, type MeetingEvent } from "@/types/meeting";
+
+interface MeetingProgressProps {
+  meetingId: string | number;
+}
+
+const stageLabels: Record<string, string> = {
+  downloading: "Downloading recording",
+  transcribing: "Transcribing",
+  summarizing: "Summarizing",
+  storing: "Saving results",
+};
+
+// Follows the meeting's event stream while it is queued or processing, and
+// reloads the page once processing ends
+export function MeetingProgress({ meetingId }: MeetingProgressProps) {
+  const router = useRouter();
+  const [progress, setProgress] = useState<MeetingEvent | null>(null);
+  const [status, setStatus] = useState<MeetingStatus | null>(null);
+
+  useEffect(() => {
+    // EventSource reconnects by itself and sends Last-Event-ID to resume
+    const source = new EventSource(meetingEventsUrl(meetingId));
+
+    source.addEventListener("progress", (e) => {
+      setProgress(JSON.parse((e as MessageEvent).data));
+    });
+    source.addEventListener("status", (e) => {
+      const event: MeetingEvent = JSON.parse((e as MessageEvent).data);
+      if (!event.status) return;
+      setStatus(event.status);
+      if (
+        event.status === MeetingStatus.COMPLETED ||
+        event.status === MeetingStatus.FAILED
+      ) {
+        source.close();
+        router.refresh();
+      }
+    });
+
+    return () => source.close();
+  }, [meetingId, router]);
+
+  if (status === MeetingStatus.QUEUED) {
+    return (
+      <p className="text-sm text-muted-foreground">
+        Waiting for a worker to pick up the recording...
+      </p>
+    );
+  }
+  if (!progress?.stage) {
+    return (
+      <p className="text-sm text-muted-foreground">Processing recording...</p>
+    );
+  }
+
+  const label = stageLabels[progress.stage] ?? progress.stage;
+  return (
+    <div className="space-y-2">
+      <p className="text-sm">
+        {label}
+        {progress.percent !== undefined && ` ${progress.percent}%`}
+      </p>
+      {progress.percent !== undefined && (
+        <div className="h-2 w-full overflow-hidden rounded-full bg-muted">
+          <div
+            className="h-full bg-primary transition-all"
+            style={{ width: `${progress.percent}%` }}
+          />
+        </div>
+      )}
+    </div>
+  );
+}
@@ -26,6 +26,24 @@ export async function getMeeting(id: string | number): Promise<Meeting> {
   return response.json();
 }
 
+// Large transcripts are kept out of the meeting itself and loaded on their own
+export async function getTranscript(id: string | number): Promise<string> {
+  const response = await fetch(`${BASE_URL}/api/v1/meetings/${id}/transcript`, {
+    cache: "no-cache",
+  });
+
+  if (!response.ok) {
+    throw new Error("Failed to fetch transcript");
+  }
+
+  return response.text();
+}
+
+// Server-Sent Events with the meeting's processing progress and status changes
+export function meetingEventsUrl(id: string | number): string {
+  return `${BASE_URL}/api/v1/meetings/${id}/events`;
+}
+
 export async function createMeeting(
   input: CreateMeetingInput
 ): Promise<Meeting> {
@@ -46,9 +64,11 @@ export async function createMeeting(
 
 export type UpdateMeetingInput = Partial<
   CreateMeetingInput & {
+    recording_file_id?: number | null;
     recording_path?: string | null;
     recording_size_bytes?: number | null;
     recording_duration_seconds?: number | null;
+    reuse_transcript?: boolean;
   }
 >;
 
@@ -81,10 +101,24 @@ export async function deleteMeeting(id: string | number): Promise<void> {
   }
 }
 
+export interface AudioMetadata {
+  format: "mp3" | "wav" | "m4a";
+  mime_type: string;
+  duration_seconds: number;
+  sample_rate: number;
+  channels: number;
+  bitrate: number;
+}
+
 export interface UploadFileResponse {
   message: string;
+  id: number;
   file_id: string;
   filename: string;
+  size: number;
+  metadata: AudioMetadata;
+  duplicate: boolean;
+  existing_meeting_id?: number;
 }
 
 export async function uploadFile(
@@ -1,10 +1,17 @@
 export enum MeetingStatus {
   CREATED = "created",
+  QUEUED = "queued",
   PROCESSING = "processing",
   COMPLETED = "completed",
   FAILED = "failed",
 }
 
+export enum StorageTier {
+  HOT = "hot",
+  ARCHIVED = "archived",
+  RESTORING = "restoring",
+}
+
 export interface Meeting {
   id: number;
 
@@ -18,12 +25,22 @@ export interface Meeting {
   scheduled_at?: string | null;
 
   // Recording Details
+  recording_file_id?: number | null;
   recording_path?: string | null;
   recording_size_bytes?: number | null;
   recording_duration_seconds?: number | null;
+  recording_mime_type?: string | null;
+  recording_sample_rate?: number | null;
+  recording_channels?: number | null;
+  recording_bitrate?: number | null;
+  recording_tier?: StorageTier;
 
-  // AI Results
+  // AI Results. transcript is only inline for small transcripts on a single
+  // meeting; has_transcript says whether /meetings/:id/transcript has one.
   transcript?: string | null;
+  transcript_key?: string | null;
+  transcript_size_bytes?: number | null;
+  has_transcript?: boolean;
   summary?: string | null;
 
   // JSONB
@@ -31,15 +48,36 @@ export interface Meeting {
 
   // Status
   status: MeetingStatus;
+  failure_reason?: string | null;
+  // Only on update responses: whether the update queued the recording
+  enqueued?: boolean;
 
   // Ownership
   user_id?: number | null;
 
+  // Retention
+  legal_hold?: boolean;
+  recording_retention_days?: number | null;
+  transcript_retention_days?: number | null;
+  recording_expired_at?: string | null;
+  transcript_expired_at?: string | null;
+
   // Timestamps
   created_at: string;
   updated_at: string;
 }
 
+// Streamed from /meetings/:id/events while a meeting is processed
+export interface MeetingEvent {
+  id?: number;
+  type: "progress" | "status";
+  stage?: string;
+  percent?: number;
+  status?: MeetingStatus;
+  message?: string;
+  at: string;
+}
+
 export type CreateMeetingInput = {
   title: string;
   description?: string | null;